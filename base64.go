@@ -0,0 +1,43 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/base64"
+	"regexp"
+)
+
+// Matches hexadecimal strings, e.g. digests and UUIDs without dashes.
+var hexadecimal = regexp.MustCompile(`^[0-9A-Fa-f]*$`)
+
+// Returns true if a string is at least config.base64Min long and is padded
+// standard base64, which encoding/json decodes into []byte. Hexadecimal
+// strings are also valid base64 but aren't taken to be.
+func isBase64(v string) bool {
+	if len(v) < config.base64Min || len(v)%4 != 0 || hexadecimal.MatchString(v) {
+		return false
+	}
+
+	_, err := base64.StdEncoding.DecodeString(v)
+	return err == nil
+}
+
+// Returns true if a string field's values were all base64 encoded.
+func (t *Tree) base64() bool {
+	s := t.values()
+	return config.base64 && t.Type == String && s != nil && s.Strings > 0 && s.Base64Strings == s.Strings
+}
@@ -0,0 +1,93 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsBase64(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.base64 = true
+		c.base64Min = 8
+	})()
+
+	testCases := []struct {
+		Source string
+		Valid  bool
+	}{
+		{"Zm9vYmFyYmF6", true},
+		{"Zm9vYmFyYg==", true},
+		{"Zm9vYmFy", true},
+		{"Zm9vYg==", true},
+		{"Zm9v", false},
+		{"Zm9vYmFyYg", false},
+		{"Zm9vYmFy-_==", false},
+		{"Zm9v YmFyYmF6", false},
+		{"not base64!!", false},
+		// Hexadecimal digests and IDs aren't base64.
+		{"da39a3ee5e6b4b0d3255bfef95601890afd80709", false},
+		{"0123456789ABCDEF", false},
+		{"0123456789ABCDEG", true},
+	}
+
+	for _, testCase := range testCases {
+		if isBase64(testCase.Source) != testCase.Valid {
+			t.Errorf("Source: %q Expected: %t", testCase.Source, testCase.Valid)
+		}
+	}
+}
+
+func TestBase64Format(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.base64 = true
+		c.base64Min = 8
+	})()
+
+	tree, err := Parse(`[
+		{"signature": "Zm9vYmFyYmF6", "thumbs": ["Zm9vYmFyYg==", "YmF6YmF6YmF6"], "short": "Zm9v", "mixed": "Zm9vYmFyYmF6"},
+		{"signature": "YmF6YmF6YmF6", "thumbs": ["YmF6YmF6YmF6"], "short": "YmF6", "mixed": "foo"}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "type _ []struct {\n" +
+		"\tMixed     string   `json:\"mixed\"`\n" +
+		"\tShort     string   `json:\"short\"`\n" +
+		"\tSignature []byte   `json:\"signature\"`\n" +
+		"\tThumbs    [][]byte `json:\"thumbs\"`\n" +
+		"}\n"
+
+	formatted, diagnostics, err := tree.FormatDiagnostics()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(formatted) != expected {
+		t.Errorf("Expected: %q Got: %q", expected, formatted)
+	}
+
+	expectedDiagnostics := []string{
+		".signature: base64 encoded, declared as []byte",
+		".thumbs: base64 encoded, declared as []byte",
+	}
+	if !reflect.DeepEqual(diagnostics, expectedDiagnostics) {
+		t.Errorf("Expected: %q Got: %q", expectedDiagnostics, diagnostics)
+	}
+}
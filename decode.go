@@ -0,0 +1,93 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"sort"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Returns an identifier with its first letter in lower case, for
+// declarations named after an exported type.
+func unexported(name string) string {
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToLower(r)) + name[size:]
+}
+
+// Returns the keys of required fields, those present in every object
+// observed, by the path of their object. Fields of unions, tuples and
+// recursive objects aren't required, as recursive objects may only have some
+// of the fields of the object they repeat.
+func (t *Tree) required(path string, required map[string][]string) {
+	if t.Type != Struct {
+		return
+	}
+
+	for _, child := range t.Children {
+		if !child.optional(t) {
+			required[path] = append(required[path], string(child.Name))
+		}
+		child.required(joinPath(path, string(child.Name)), required)
+	}
+}
+
+// Declares a function decoding the top level type from a reader, which
+// rejects unknown fields and reports required fields which are missing by
+// their location in the document. Named types declared for its fields are
+// decoded as part of it, they have no function of their own.
+func (f *formatter) decoder(t *Tree) string {
+	name := t.Name.String()
+	prefix := unexported(name)
+
+	required := make(map[string][]string)
+	t.required(".", required)
+
+	f.imports["bytes"] = true
+	f.imports["encoding/json"] = true
+	f.imports["io"] = true
+
+	var paths []string
+	for path := range required {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var keys []requiredKeys
+	for _, path := range paths {
+		keys = append(keys, requiredKeys{Path: path, Keys: required[path]})
+	}
+
+	if len(keys) > 0 {
+		f.imports["fmt"] = true
+		f.imports["sort"] = true
+		f.imports["strconv"] = true
+		f.imports["strings"] = true
+	}
+
+	return f.execute("decode.tmpl", struct {
+		Name     string
+		Prefix   string
+		Required []requiredKeys
+	}{name, prefix, keys})
+}
+
+// The keys of the required fields of objects at a path.
+type requiredKeys struct {
+	Path string
+	Keys []string
+}
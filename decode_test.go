@@ -0,0 +1,89 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRequired(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.decode = true
+		c.recursive = true
+	})()
+
+	tree, err := Parse(`[{"id": 1, "items": [{"sku": "a"}, {"sku": "b"}], "parent": {"parent": null, "id": 2}}, {"id": 3, "items": [{"sku": "c"}]}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree.DetectRecursion()
+
+	required := make(map[string][]string)
+	tree.required(".", required)
+
+	// The parent is an element too, and has no items.
+	expected := map[string][]string{".": {"id"}, ".items": {"sku"}}
+	if !reflect.DeepEqual(required, expected) {
+		t.Errorf("Expected: %v Got: %v", expected, required)
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.decode = true
+	})()
+
+	testCases := []struct {
+		Source   string
+		Expected []string
+	}{
+		// Without required fields the document is only decoded strictly.
+		{`[1, 2]`, []string{
+			"import (\n\t\"bytes\"\n\t\"encoding/json\"\n\t\"io\"\n)\n",
+			"func DecodeOrder(r io.Reader) (*Order, error) {\n",
+			"\tdecoder.DisallowUnknownFields()\n",
+		}},
+		{`{"id": 1, "lines": [{"sku": "a"}]}`, []string{
+			"\t\"sort\"\n\t\"strconv\"\n\t\"strings\"\n)\n",
+			"type Order struct {\n",
+			"\tif missing := orderMissing(value, \".\", \"\", nil); len(missing) > 0 {\n",
+			"var orderRequired = map[string][]string{\n\t\".\":      {\"id\", \"lines\"},\n\t\".lines\": {\"sku\"},\n}\n",
+			"func orderMissing(v interface{}, path, location string, missing []string) []string {\n\tswitch",
+		}},
+	}
+
+	for _, testCase := range testCases {
+		tree, err := Parse(testCase.Source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree.Name = "Order"
+
+		formatted, err := tree.Format()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, expected := range testCase.Expected {
+			if !strings.Contains(string(formatted), expected) {
+				t.Errorf("Source: %s Expected: %q Got: %q", testCase.Source, expected, formatted)
+			}
+		}
+	}
+}
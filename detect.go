@@ -0,0 +1,292 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// A detector classifies string values. Fields whose values all match an
+// enabled detector are declared with the detector's type and documented with
+// its description.
+type Detector struct {
+	// Name used to enable the detector and configure its type.
+	Name string
+
+	// Description of the values detected, used in doc comments.
+	Description string
+
+	Match func(v string) bool
+
+	// Value matched, used in sample documents.
+	Sample string
+
+	// Go type used by default, qualified by its import path if it has one,
+	// e.g.: net/netip.Addr.
+	Type string
+
+	// Declaration required by the default type, if any, and the packages the
+	// declaration imports.
+	Declaration string
+	Imports     []string
+}
+
+// Detectors in the order they're tried.
+var detectors []*Detector
+
+// Adds a detector to the end of the pipeline.
+func RegisterDetector(d *Detector) {
+	detectors = append(detectors, d)
+}
+
+func init() {
+	uuid := regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	duration := regexp.MustCompile(`^P(\d+(\.\d+)?Y)?(\d+(\.\d+)?M)?(\d+(\.\d+)?W)?(\d+(\.\d+)?D)?(T(\d+(\.\d+)?H)?(\d+(\.\d+)?M)?(\d+(\.\d+)?S)?)?$`)
+	color := regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{4}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)
+
+	RegisterDetector(&Detector{
+		Name:        "uuid",
+		Description: "UUID",
+		Match:       uuid.MatchString,
+		Sample:      "123e4567-e89b-12d3-a456-426614174000",
+		Type:        "string",
+	})
+
+	RegisterDetector(&Detector{
+		Name:        "url",
+		Description: "URL",
+		Match: func(v string) bool {
+			u, err := url.Parse(v)
+			return err == nil && u.Scheme != "" && u.Host != ""
+		},
+		Sample: "https://example.com/",
+		Type:   "URL",
+		Declaration: `// A URL decoded from and encoded as a JSON string.
+type URL struct {
+	url.URL
+}
+
+func (u *URL) UnmarshalText(text []byte) error {
+	parsed, err := url.Parse(string(text))
+	if err != nil {
+		return err
+	}
+	u.URL = *parsed
+	return nil
+}
+
+func (u URL) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+`,
+		Imports: []string{"net/url"},
+	})
+
+	RegisterDetector(&Detector{
+		Name:        "email",
+		Description: "email address",
+		Match: func(v string) bool {
+			address, err := mail.ParseAddress(v)
+			return err == nil && address.Address == v
+		},
+		Sample: "user@example.com",
+		Type:   "string",
+	})
+
+	RegisterDetector(&Detector{
+		Name:        "ip",
+		Description: "IP address",
+		Match: func(v string) bool {
+			_, err := netip.ParseAddr(v)
+			return err == nil
+		},
+		Sample: "192.0.2.1",
+		Type:   "net/netip.Addr",
+	})
+
+	RegisterDetector(&Detector{
+		Name:        "duration",
+		Description: "ISO 8601 duration",
+		Match: func(v string) bool {
+			return duration.MatchString(v) && v != "P" && !strings.HasSuffix(v, "T")
+		},
+		Sample: "PT1H30M",
+		Type:   "string",
+	})
+
+	RegisterDetector(&Detector{
+		Name:        "color",
+		Description: "hex colour",
+		Match:       color.MatchString,
+		Sample:      "#336699",
+		Type:        "string",
+	})
+
+	RegisterDetector(&Detector{
+		Name:        "time",
+		Description: "RFC 3339 timestamp",
+		Match: func(v string) bool {
+			_, err := time.Parse(time.RFC3339Nano, v)
+			return err == nil
+		},
+		Sample: "2021-04-05T10:00:00Z",
+		Type:   "time.Time",
+	})
+}
+
+// Returns the names of registered detectors for usage.
+func detectorNames() string {
+	var names []string
+	for _, d := range detectors {
+		names = append(names, d.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Parses comma separated lists of detectors to enable and name=type pairs.
+func (c *Config) parseDetectors(enabled, types string) error {
+	known := make(map[string]bool)
+	for _, d := range detectors {
+		known[d.Name] = true
+	}
+
+	c.detectors = make(map[string]bool)
+	for _, name := range strings.Split(enabled, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if !known[name] {
+			return fmt.Errorf("unknown detector %q", name)
+		}
+		c.detectors[name] = true
+	}
+
+	c.detectorTypes = make(map[string]string)
+	for _, pair := range strings.Split(types, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+
+		name := strings.SplitN(pair, "=", 2)
+		if len(name) != 2 || !known[name[0]] || name[1] == "" {
+			return fmt.Errorf("invalid detector type %q", pair)
+		}
+		c.detectorTypes[name[0]] = name[1]
+	}
+
+	return nil
+}
+
+// Records which enabled detectors a string value matches.
+func (s *Stats) detect(v string) {
+	for _, d := range detectors {
+		if !config.detectors[d.Name] || !d.Match(v) {
+			continue
+		}
+
+		if s.Detected == nil {
+			s.Detected = make(map[string]int)
+		}
+		s.Detected[d.Name]++
+	}
+}
+
+// Returns the first enabled detector which matched every value of a string
+// field, or the detector it names, nil if there is none.
+func (t *Tree) detector() *Detector {
+	if t.Detector != "" {
+		for _, d := range detectors {
+			if d.Name == t.Detector {
+				return d
+			}
+		}
+		return nil
+	}
+
+	s := t.values()
+	if t.Type != String || s == nil || s.Strings == 0 {
+		return nil
+	}
+
+	for _, d := range detectors {
+		if config.detectors[d.Name] && s.Detected[d.Name] == s.Strings {
+			return d
+		}
+	}
+	return nil
+}
+
+// Returns the Go type of values a detector matched, configured or its default.
+func (d *Detector) goType() string {
+	if typ, ok := config.detectorTypes[d.Name]; ok {
+		return typ
+	}
+	return d.Type
+}
+
+// Returns the type of a string field whose values all matched an enabled
+// detector, the first such detector in the pipeline is used.
+func (f *formatter) detected(t *Tree) (name string, ok bool) {
+	d := t.detector()
+	if d == nil {
+		return "", false
+	}
+
+	f.doc = append(f.doc, "Detected: "+d.Description+".")
+
+	qualified, configured := config.detectorTypes[d.Name]
+	if !configured && d.Declaration != "" {
+		// The declaration is renamed if its type's name is taken.
+		name, declared := f.detectorTypes[d.Name]
+		if !declared {
+			name = f.names.declare(d.Type)
+			f.detectorTypes[d.Name] = name
+			f.decls = append(f.decls, f.parseRenamed(d.Declaration, map[string]string{d.Type: name}))
+			for _, path := range d.Imports {
+				f.imports[path] = true
+			}
+		}
+		qualified = name
+	} else if !configured {
+		qualified = d.Type
+	}
+
+	return f.qualify(qualified), true
+}
+
+// Returns a type qualified by its import path as it is referred to in source,
+// importing its package. For example *net/netip.Addr is *netip.Addr.
+func (f *formatter) qualify(qualified string) string {
+	pointer := strings.TrimLeft(qualified, "*")
+	prefix := qualified[:len(qualified)-len(pointer)]
+
+	dot := strings.LastIndex(pointer, ".")
+	if dot < 0 {
+		return qualified
+	}
+
+	path := pointer[:dot]
+	f.imports[path] = true
+
+	return prefix + path[strings.LastIndex(path, "/")+1:] + pointer[dot:]
+}
@@ -0,0 +1,164 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectorMatch(t *testing.T) {
+	testCases := []struct {
+		Detector string
+		Source   string
+		Match    bool
+	}{
+		{"uuid", "123e4567-e89b-12d3-a456-426614174000", true},
+		{"uuid", "123e4567e89b12d3a456426614174000", false},
+		{"url", "https://example.com/path?q=1", true},
+		{"url", "/relative/path", false},
+		{"url", "example.com", false},
+		{"email", "gopher@example.com", true},
+		{"email", "Gopher <gopher@example.com>", false},
+		{"email", "gopher", false},
+		{"ip", "192.168.0.1", true},
+		{"ip", "2001:db8::1", true},
+		{"ip", "192.168.0", false},
+		{"duration", "P1Y2M3DT4H5M6S", true},
+		{"duration", "PT0.5S", true},
+		{"duration", "P", false},
+		{"duration", "P1DT", false},
+		{"color", "#fff", true},
+		{"color", "#A0B0C0FF", true},
+		{"color", "#ggg", false},
+		{"color", "fff", false},
+		{"time", "2021-04-05T10:00:00Z", true},
+		{"time", "2021-04-05T10:00:00.123+02:00", true},
+		{"time", "2021-04-05", false},
+		{"time", "2021-04-05 10:00:00", false},
+	}
+
+	byName := make(map[string]*Detector)
+	for _, d := range detectors {
+		byName[d.Name] = d
+	}
+
+	for _, testCase := range testCases {
+		if byName[testCase.Detector].Match(testCase.Source) != testCase.Match {
+			t.Errorf("Detector: %s Source: %q Expected: %t", testCase.Detector, testCase.Source, testCase.Match)
+		}
+	}
+}
+
+func TestParseDetectors(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		if err := c.parseDetectors("", ""); err != nil {
+			t.Fatal(err)
+		}
+	})()
+
+	testCases := []struct {
+		Enabled, Types string
+		Valid          bool
+	}{
+		{"uuid,ip", "", true},
+		{"uuid", "uuid=github.com/google/uuid.UUID", true},
+		{"mac", "", false},
+		{"uuid", "mac=string", false},
+		{"uuid", "uuid", false},
+	}
+
+	for _, testCase := range testCases {
+		err := config.parseDetectors(testCase.Enabled, testCase.Types)
+		if (err == nil) != testCase.Valid {
+			t.Errorf("Enabled: %q Types: %q Expected valid: %t Got: %v", testCase.Enabled, testCase.Types, testCase.Valid, err)
+		}
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		if err := c.parseDetectors("uuid,url,ip,color", "uuid=github.com/google/uuid.UUID"); err != nil {
+			t.Fatal(err)
+		}
+	})()
+
+	tree, err := Parse(`[
+		{"id": "123e4567-e89b-12d3-a456-426614174000", "home": "https://example.com", "addrs": ["10.0.0.1"], "name": "foo"},
+		{"id": "123e4567-e89b-12d3-a456-426614174001", "home": "http://example.org/x", "addrs": ["::1", "10.0.0.2"], "name": "#fff"}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	formatted, err := tree.Format()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "import (\n" +
+		"\t\"github.com/google/uuid\"\n" +
+		"\t\"net/netip\"\n" +
+		"\t\"net/url\"\n" +
+		")\n" +
+		"\n" +
+		"type _ []struct {\n" +
+		"\t// Detected: IP address.\n" +
+		"\tAddrs []netip.Addr `json:\"addrs\"`\n" +
+		"\t// Detected: URL.\n" +
+		"\tHome URL `json:\"home\"`\n" +
+		"\t// Detected: UUID.\n" +
+		"\tId   uuid.UUID `json:\"id\"`\n" +
+		"\tName string    `json:\"name\"`\n" +
+		"}\n"
+
+	if !strings.HasPrefix(string(formatted), expected) {
+		t.Errorf("Expected: %q Got: %q", expected, formatted)
+	}
+
+	if !strings.Contains(string(formatted), "type URL struct {\n\turl.URL\n}\n") {
+		t.Errorf("Expected URL declaration Got: %q", formatted)
+	}
+}
+
+// Types declared for detectors are renamed if their name is taken.
+func TestDetectNames(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.tuples = true
+		c.tupleMax = 8
+		if err := c.parseDetectors("url", ""); err != nil {
+			t.Fatal(err)
+		}
+	})()
+
+	tree, err := Parse(`{"a": {"URL": ["a", 1]}, "link": "https://example.com"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	formatted, err := tree.Format()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{"\tLink URL2 `json:\"link\"`\n", "type URL struct {\n\tField0 string\n", "type URL2 struct {\n\turl.URL\n}\n", "func (u *URL2) UnmarshalText("} {
+		if !strings.Contains(string(formatted), expected) {
+			t.Errorf("Expected: %q Got: %q", expected, formatted)
+		}
+	}
+	vet(t, formatted)
+}
@@ -0,0 +1,105 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Annotations which can be added to the doc comments of fields, in the order
+// they're written.
+var annotations = []string{"example", "presence", "nullable"}
+
+// Parses a comma separated list of annotations to enable.
+func (c *Config) parseDoc(enabled string) error {
+	known := make(map[string]bool)
+	for _, name := range annotations {
+		known[name] = true
+	}
+
+	c.doc = make(map[string]bool)
+	for _, name := range strings.Split(enabled, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if !known[name] {
+			return fmt.Errorf("unknown annotation %q", name)
+		}
+		c.doc[name] = true
+	}
+
+	return nil
+}
+
+// Records the first value observed as an example, if examples are enabled.
+func (s *Stats) observeExample(v interface{}) {
+	if s == nil || !config.doc["example"] || s.Example != "" {
+		return
+	}
+
+	switch i := v.(type) {
+	case string:
+		// Long strings are truncated.
+		if utf8.RuneCountInString(i) > config.docExampleMax {
+			runes := []rune(i)
+			i = string(runes[:config.docExampleMax]) + "..."
+		}
+		s.Example = strconv.Quote(i)
+	case json.Number:
+		s.Example = string(i)
+	case bool:
+		s.Example = strconv.FormatBool(i)
+	}
+}
+
+// Returns the line of a field's doc comment describing the values observed of
+// it, with the annotations enabled: an example value, the percentage of the
+// objects of its parent it was present in and whether it was ever null.
+// Returns an empty string if there's nothing to describe.
+func (t *Tree) annotation(parent *Tree) string {
+	// The values of tuples are arrays, not the values of their positions.
+	s := t.values()
+	if t.Type == Tuple {
+		if s = t.Stats; t.List && s != nil {
+			s = s.Elements
+		}
+	}
+	if s == nil {
+		return ""
+	}
+
+	var parts []string
+	if config.doc["example"] && s.Example != "" {
+		parts = append(parts, "Example: "+s.Example)
+	}
+	if p := parent.values(); config.doc["presence"] && p != nil && p.Count > 0 && t.Stats != nil {
+		parts = append(parts, fmt.Sprintf("present in %d%% of samples", t.Stats.Count*100/p.Count))
+	}
+	if config.doc["nullable"] && s.Kinds["null"] > 0 {
+		parts = append(parts, "nullable")
+	}
+
+	line := strings.Join(parts, "; ")
+	if line == "" {
+		return ""
+	}
+	return strings.ToUpper(line[:1]) + line[1:]
+}
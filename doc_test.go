@@ -0,0 +1,133 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDocFormat(t *testing.T) {
+	testCases := []struct {
+		Enabled  string
+		Source   string
+		Expected string
+	}{
+		{"example", `{"a": "foo", "b": 1.5, "c": true, "d": null}`,
+			"type _ struct {\n" +
+				"\t// Example: \"foo\"\n" +
+				"\tA string `json:\"a\"`\n" +
+				"\t// Example: 1.5\n" +
+				"\tB float64 `json:\"b\"`\n" +
+				"\t// Example: true\n" +
+				"\tC bool        `json:\"c\"`\n" +
+				"\tD interface{} `json:\"d\"`\n" +
+				"}\n"},
+		// Long examples are truncated.
+		{"example", `{"a": "abcdefghijklmnopqrstuvwxyz"}`,
+			"type _ struct {\n\t// Example: \"abcdefghij...\"\n\tA string `json:\"a\"`\n}\n"},
+		{"presence,nullable", `[{"a": 1, "b": null}, {"b": 2}, {"b": 3}]`,
+			"type _ []struct {\n" +
+				"\t// Present in 33% of samples\n" +
+				"\tA int64 `json:\"a\"`\n" +
+				"\t// Present in 100% of samples; nullable\n" +
+				"\tB interface{} `json:\"b\"`\n" +
+				"}\n"},
+		{"example,presence", `{"a": [{"b": "x"}, {"c": 1}]}`,
+			"type _ struct {\n" +
+				"\t// Present in 100% of samples\n" +
+				"\tA []struct {\n" +
+				"\t\t// Example: \"x\"; present in 50% of samples\n" +
+				"\t\tB string `json:\"b\"`\n" +
+				"\t\t// Example: 1; present in 50% of samples\n" +
+				"\t\tC int64 `json:\"c\"`\n" +
+				"\t} `json:\"a\"`\n" +
+				"}\n"},
+	}
+
+	for _, testCase := range testCases {
+		restore := withConfig(func(c *Config) {
+			c.parseDoc(testCase.Enabled)
+			c.docExampleMax = 10
+		})
+
+		tree, err := Parse(testCase.Source)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		formatted, err := tree.Format()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(formatted) != testCase.Expected {
+			t.Errorf("Source: %s Expected: %q Got: %q", testCase.Source, testCase.Expected, formatted)
+		}
+		restore()
+	}
+}
+
+func TestParseDoc(t *testing.T) {
+	var c Config
+	if err := c.parseDoc("example, nullable"); err != nil {
+		t.Fatal(err)
+	}
+	if !c.doc["example"] || !c.doc["nullable"] || c.doc["presence"] {
+		t.Errorf("Expected: example and nullable Got: %v", c.doc)
+	}
+
+	if err := c.parseDoc("examples"); err == nil {
+		t.Errorf("Expected error parsing unknown annotation")
+	}
+}
+
+// Tuples are documented by their positions, not by the value of the first.
+func TestDocTuple(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.parseDoc("example")
+		c.docExampleMax = 10
+		c.tuples = true
+		c.tupleMax = 8
+	})()
+
+	tree, err := Parse(`{"rate": ["EUR", 12.5, true]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	formatted, err := tree.Format()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "type _ struct {\n" +
+		"\tRate Rate `json:\"rate\"`\n" +
+		"}\n" +
+		"\n" +
+		"type Rate struct {\n" +
+		"\t// Example: \"EUR\"\n" +
+		"\tField0 string\n" +
+		"\t// Example: 12.5\n" +
+		"\tField1 float64\n" +
+		"\t// Example: true\n" +
+		"\tField2 bool\n" +
+		"}\n"
+	if !strings.Contains(string(formatted), expected) {
+		t.Errorf("Expected: %q Got: %q", expected, formatted)
+	}
+}
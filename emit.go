@@ -0,0 +1,322 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// An emitter generates declarations in some language from a type graph.
+type Emitter interface {
+	// Returns the declarations of the graph's types and notes about them
+	// for the user.
+	Emit(g *Graph) (source []byte, diagnostics []string, err error)
+}
+
+// Emitters by the name used to select them with -lang.
+var emitters = make(map[string]Emitter)
+
+// Adds an emitter which can be selected by name. jsongen is a command rather
+// than a library, so emitters are registered by the init functions of files
+// added to package main, as the built in emitters are.
+func RegisterEmitter(name string, e Emitter) {
+	emitters[name] = e
+}
+
+// Returns the names of registered emitters for usage.
+func emitterNames() string {
+	var names []string
+	for name := range emitters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// Returns true if stats are required by the emitter selected, which is true
+// of all but Go's: they're used to find optional and nullable fields.
+func emitterStats() bool {
+	return config.lang != "" && config.lang != "go"
+}
+
+func init() {
+	RegisterEmitter("go", goEmitter{})
+}
+
+// Emits Go source. Go isn't generated from the graph: its declarations,
+// references and fields are ignored and the tree the graph was built from
+// is formatted instead, as the Go types chosen depend on options which
+// consult the stats of the tree and nested structs are anonymous in Go, so
+// only types Go must name are declared. Those are named by a namer as the
+// graph's declarations are, after their fields, and the top level type has
+// the graph's name unless the tree is unnamed, when it's _ so the type can
+// be renamed where it's pasted.
+type goEmitter struct{}
+
+func (goEmitter) Emit(g *Graph) ([]byte, []string, error) {
+	return g.tree.FormatDiagnostics()
+}
+
+// A type graph is a language independent description of the types a tree
+// requires: a declaration for each struct and union, named after their
+// fields, and references to them.
+type Graph struct {
+	// Name of the top level type and a reference to it, which is a
+	// declaration of that name if the tree is a struct.
+	Name string
+	Root *Ref
+
+	// Declarations in the order they're first referred to, variants follow
+	// their union.
+	Types []*Decl
+
+	// The tree the graph was built from, which only the Go emitter reads.
+	tree *Tree
+}
+
+// A declaration of a struct or a union. Unions have a variant for each value
+// of their discriminator field, variants are structs with the discriminator
+// and the value they're for.
+type Decl struct {
+	Name string
+	Type Type
+
+	Fields   []*Field
+	Variants []*Decl
+
+	Discriminator string
+	Value         string
+}
+
+// A field of a struct or a position of a tuple. Name is an identifier,
+// sanitized as Go fields are, and Key is the JSON key.
+type Field struct {
+	Name string
+	Key  string
+	Type *Ref
+
+	// Optional fields were missing from some of the objects observed.
+	Optional bool
+
+	// Lines of documentation.
+	Doc []string
+}
+
+// A reference to a type by a field, or the top level. Structs and unions
+// refer to their declaration, tuples have their positions and enums their
+// values. The empty interface has the JSON kinds it was observed holding.
+type Ref struct {
+	Type     Type
+	List     bool
+	Nullable bool
+
+	Decl      *Decl
+	Positions []*Ref
+	Values    []string
+	Kinds     []string
+
+	// Recursive references refer to the declaration of a struct they're
+	// nested in.
+	Recursive bool
+
+	// Range of the integers observed, nil if stats weren't gathered.
+	Min, Max *big.Int
+
+	// Name of the detector which matched every string observed, e.g. time,
+	// and whether they were all base64 encoded bytes.
+	Format string
+	Bytes  bool
+}
+
+// JSON kinds in the order they're listed.
+var jsonKinds = []string{"bool", "number", "string", "object", "array", "null"}
+
+// Returns the type graph of the tree. The top level type is named after the
+// tree, as it is in Go, or Root if the tree is unnamed.
+func (t *Tree) Graph() *Graph {
+	name := t.Name.String()
+	if name == "_" {
+		name = "Root"
+	}
+
+	b := &grapher{
+		g:     &Graph{Name: name, tree: t},
+		names: newNamer(""),
+		path:  ".",
+		refs:  make(map[string]*Decl),
+		root:  t,
+	}
+
+	// The top level struct is declared with the graph's name, other types
+	// are referred to by that name and their declarations are named after
+	// it, e.g. RootElement.
+	if t.Type != Struct || t.List {
+		b.names.taken[name] = true
+	}
+
+	b.g.Root = b.ref(t, t.List, t.Nullable)
+	return b.g
+}
+
+// Builds a type graph.
+type grapher struct {
+	g     *Graph
+	names *namer
+
+	// Path of the field being built and the declarations of structs by
+	// path, which recursive types refer to.
+	path string
+	refs map[string]*Decl
+
+	root *Tree
+}
+
+// Returns the name of the declaration of a tree, after its field.
+func (b *grapher) name(t *Tree, fallback string) string {
+	if t == b.root {
+		if t.List || t.Type != Struct {
+			return b.g.Name + "Element"
+		}
+		return b.g.Name
+	}
+
+	name := t.Name.String()
+	if name == "_" {
+		name = fallback
+	}
+	return name
+}
+
+// Returns a reference to the type of a tree's values, a list of them if
+// list is set, declaring any types required.
+func (b *grapher) ref(t *Tree, list, nullable bool) *Ref {
+	r := &Ref{Type: t.Type, List: list, Nullable: nullable}
+	switch t.Type {
+	case Int:
+		if s := t.values(); s != nil {
+			r.Min, r.Max = s.Min, s.Max
+		}
+	case String:
+		r.Values, _ = t.enumValues()
+		if d := t.detector(); d != nil {
+			r.Format = d.Name
+		}
+		r.Bytes = t.base64()
+	case Struct:
+		r.Decl = b.structDecl(t)
+	case Tuple:
+		for _, position := range t.Children {
+			r.Positions = append(r.Positions, b.ref(position, position.List, position.Nullable))
+		}
+	case Union:
+		r.Decl = b.union(t)
+	case Recursive:
+		// Recursive types which aren't lists are pointers in Go, as the
+		// deepest level is null or missing.
+		r.Type = Interface
+		if decl, ok := b.refs[t.Ref]; ok {
+			r.Type = Struct
+			r.Decl = decl
+			r.Nullable = nullable || !list
+			r.Recursive = true
+		}
+	}
+
+	if r.Type == Interface {
+		r.Kinds = kinds(t)
+	}
+	return r
+}
+
+// Returns true if the integers referred to are beyond the range of int64.
+func (r *Ref) big() bool {
+	return r.Min != nil && !(r.Min.IsInt64() && r.Max.IsInt64())
+}
+
+// Returns the JSON kinds of the values of a tree which were observed.
+func kinds(t *Tree) (kinds []string) {
+	s := t.Stats
+	if t.List && s != nil {
+		s = s.Elements
+	}
+	if s == nil {
+		return nil
+	}
+
+	for _, kind := range jsonKinds {
+		if s.Kinds[kind] > 0 {
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds
+}
+
+// Declares a struct, named after its field.
+func (b *grapher) structDecl(t *Tree) *Decl {
+	d := &Decl{Name: b.names.declare(b.name(t, "Object")), Type: Struct}
+	b.refs[b.path] = d
+
+	// Declarations precede those of their fields.
+	b.g.Types = append(b.g.Types, d)
+	d.Fields = b.fields(t)
+
+	return d
+}
+
+// Declares a union and a struct for each of its variants, named after its
+// field and the value of the discriminator.
+func (b *grapher) union(t *Tree) *Decl {
+	d := &Decl{Name: b.names.declare(b.name(t, "Union")), Type: Union, Discriminator: string(t.Discriminator)}
+	b.g.Types = append(b.g.Types, d)
+
+	for _, variant := range t.Children {
+		v := &Decl{
+			Name:          b.names.declare(d.Name + constantSuffix(string(variant.Name))),
+			Type:          Struct,
+			Discriminator: d.Discriminator,
+			Value:         string(variant.Name),
+		}
+		d.Variants = append(d.Variants, v)
+		b.g.Types = append(b.g.Types, v)
+		v.Fields = b.fields(variant)
+	}
+
+	return d
+}
+
+// Returns the fields of a struct.
+func (b *grapher) fields(t *Tree) (fields []*Field) {
+	for _, child := range t.Children {
+		field := &Field{Name: child.Name.String(), Key: string(child.Name), Optional: child.optional(t)}
+		if d := child.detector(); d != nil {
+			field.Doc = append(field.Doc, "Detected: "+d.Description+".")
+		}
+		if line := child.annotation(t); line != "" {
+			field.Doc = append(field.Doc, line)
+		}
+
+		path := b.path
+		b.path = joinPath(path, string(child.Name))
+		field.Type = b.ref(child, child.List, child.Nullable)
+		b.path = path
+
+		fields = append(fields, field)
+	}
+	return
+}
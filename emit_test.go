@@ -0,0 +1,136 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// Lists the declarations of a graph and the types of their fields.
+type listing struct{}
+
+func (listing) Emit(g *Graph) ([]byte, []string, error) {
+	var lines []string
+	for _, d := range g.Types {
+		line := d.Name + " " + d.Type.String()
+		for _, field := range d.Fields {
+			line += " " + field.Name + ":" + field.Type.Type.String()
+			if field.Type.Decl != nil {
+				line += ":" + field.Type.Decl.Name
+			}
+			if field.Optional {
+				line += "?"
+			}
+		}
+		lines = append(lines, line)
+	}
+	return []byte(strings.Join(lines, "\n")), nil, nil
+}
+
+func TestGraph(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.lang = "typescript"
+		c.unions = true
+		c.unionMax = 16
+	})()
+
+	testCases := []struct {
+		Source   string
+		Expected string
+	}{
+		{`{"a": 1}`, "Root struct A:int64"},
+		{`[{"a": 1}, {"b": "foo"}]`, "RootElement struct A:int64? B:string?"},
+		// Declarations precede those of their fields and are numbered if
+		// their names are taken.
+		{`{"a": {"b": {"a": true}}, "c": {"a": null}}`,
+			"Root struct A:struct:A C:struct:C\nA struct B:struct:B\nB struct A:bool\nC struct A:interface{}"},
+		{`{"a": {"root": {}}}`, "Root struct A:struct:A\nA struct Root:struct:Root2\nRoot2 struct"},
+		{`{"e": [{"type": "x", "a": 1}, {"type": "y", "b": 1}]}`,
+			"Root struct E:union:E\nE union\nEX struct A:int64 Type:string\nEY struct B:int64 Type:string"},
+		// The top level type is named after the tree, as it is in Go.
+		{`{"order": {"id": 1}}`, "Order struct Order:struct:Order2\nOrder2 struct Id:int64"},
+		{`[{"a": 1}]`, "OrderElement struct A:int64"},
+	}
+
+	for _, testCase := range testCases {
+		tree, err := Parse(testCase.Source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasPrefix(testCase.Expected, "Order") {
+			tree.Name = "Order"
+		}
+
+		listed, _, _ := listing{}.Emit(tree.Graph())
+		if string(listed) != testCase.Expected {
+			t.Errorf("Source: %s Expected: %q Got: %q", testCase.Source, testCase.Expected, listed)
+		}
+	}
+}
+
+// Recursive types refer to the declaration of the struct they share a shape
+// with.
+func TestGraphRecursive(t *testing.T) {
+	tree, err := Parse(`{"name": "a", "children": [{"name": "b", "children": []}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree.DetectRecursion()
+
+	g := tree.Graph()
+	children := g.Types[0].Fields[0]
+	if children.Key != "children" || children.Type.Decl != g.Types[0] || !children.Type.List {
+		t.Errorf("Expected: reference to %s Got: %+v", g.Types[0].Name, children.Type)
+	}
+}
+
+// References hold the range of integers and the format of strings, so
+// emitters needn't consult the tree.
+func TestGraphFormats(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.lang = "typescript"
+		c.base64 = true
+		c.base64Min = 8
+		c.detectors = map[string]bool{"time": true}
+	})()
+
+	tree, err := Parse(`{"id": 18446744073709551615, "at": "2020-01-02T03:04:05Z", "data": "aGVsbG8gd29ybGQ="}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields := tree.Graph().Types[0].Fields
+	if id := fields[2].Type; id.Min == nil || id.Max.String() != "18446744073709551615" || !id.big() {
+		t.Errorf("Expected: range up to 18446744073709551615 Got: %v..%v", id.Min, id.Max)
+	}
+	if at := fields[0].Type; at.Format != "time" || at.Bytes {
+		t.Errorf("Expected: %q Got: %q bytes %t", "time", at.Format, at.Bytes)
+	}
+	if data := fields[1].Type; data.Format != "" || !data.Bytes {
+		t.Errorf("Expected: bytes Got: %q bytes %t", data.Format, data.Bytes)
+	}
+}
+
+func TestRegisterEmitter(t *testing.T) {
+	RegisterEmitter("listing", listing{})
+	defer delete(emitters, "listing")
+
+	if names := emitterNames(); names != "go, listing, proto, sql, typescript" {
+		t.Errorf("Expected: %q Got: %q", "go, listing, proto, sql, typescript", names)
+	}
+}
@@ -0,0 +1,102 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Returns the distinct values of a string field if it should be declared as
+// an enum: enums are enabled, at least config.enumSamples values were
+// observed and there are no more than config.enumMax distinct values.
+func (t *Tree) enumValues() (values []string, ok bool) {
+	s := t.values()
+	if !config.enum || t.Type != String || s == nil || s.Overflow {
+		return nil, false
+	}
+
+	if len(s.Values) == 0 || len(s.Values) > config.enumMax || s.Count < config.enumSamples {
+		return nil, false
+	}
+
+	for v := range s.Values {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+
+	return values, true
+}
+
+// Declares a string type named after the field with a constant for each
+// observed value. If config.enumStrict is set an UnmarshalJSON method
+// rejecting unknown values is declared too.
+func (f *formatter) enum(t *Tree) (name string, ok bool) {
+	values, ok := t.enumValues()
+	if !ok {
+		return "", false
+	}
+
+	name = t.Name.String()
+	if name == "_" {
+		name = "Enum"
+	}
+	name = f.names.declare(name)
+
+	// Constants are named after the type and value, e.g.: StatusActive, and
+	// share the names of types.
+	constants := make([]enumConstant, len(values))
+	for idx, v := range values {
+		constants[idx] = enumConstant{Name: f.names.declare(name + constantSuffix(v)), Value: v}
+	}
+
+	if config.enumStrict {
+		f.imports["encoding/json"] = true
+		f.imports["fmt"] = true
+	}
+
+	f.decls = append(f.decls, f.execute("enum.tmpl", struct {
+		Name      string
+		Constants []enumConstant
+		Strict    bool
+	}{name, constants, config.enumStrict}))
+
+	return name, true
+}
+
+// A constant of an enum and the value it's declared as.
+type enumConstant struct {
+	Name  string
+	Value string
+}
+
+// Returns a value in title case with all but letters and digits removed,
+// "Empty" if nothing remains.
+func constantSuffix(v string) (suffix string) {
+	words := strings.FieldsFunc(v, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		suffix += strings.Title(word)
+	}
+
+	if suffix == "" {
+		suffix = "Empty"
+	}
+	return
+}
@@ -0,0 +1,314 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestEnumStats(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.enum = true
+		c.enumMax = 2
+		c.enumSamples = 1
+		c.enumStrict = false
+	})()
+
+	testCases := []struct {
+		Source string
+		Stats  *Stats
+	}{
+		{`"foo"`, &Stats{Count: 1, Strings: 1, Values: map[string]int{"foo": 1}}},
+		{`["foo", "bar", "foo"]`, &Stats{Count: 1, Elements: &Stats{Count: 3, Strings: 3, Values: map[string]int{"foo": 2, "bar": 1}}}},
+		{`["foo", "bar", "baz"]`, &Stats{Count: 1, Elements: &Stats{Count: 3, Strings: 3, Overflow: true}}},
+		{`[["foo"], ["bar", "foo"]]`, &Stats{Count: 1, Elements: &Stats{Count: 2, Elements: &Stats{Count: 3, Strings: 3, Values: map[string]int{"foo": 2, "bar": 1}}}}},
+	}
+
+	for _, testCase := range testCases {
+		tree, err := Parse(testCase.Source)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(tree.Stats, testCase.Stats) {
+			t.Errorf("Source: %s Expected: %+v Got: %+v", testCase.Source, testCase.Stats, tree.Stats)
+		}
+	}
+}
+
+// Stats must be the same whether squashed while streaming or afterwards.
+func TestEnumInfer(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.enum = true
+		c.enumMax = 3
+		c.enumSamples = 1
+		c.enumStrict = false
+	})()
+
+	source := `[
+		{"status": "active", "nested": {"kind": "a"}},
+		{"status": "suspended", "nested": {"kind": "b"}},
+		{"status": "active", "nested": {"kind": "b"}, "conflict": 1},
+		{"conflict": "foo"}
+	]`
+
+	expected, err := Parse(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tree Tree
+	if err := tree.Infer(json.NewDecoder(bytes.NewBufferString(source))); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(tree, expected) {
+		t.Errorf("Expected: %+v Got: %+v", expected, tree)
+	}
+
+	nested := tree.Children[1].Children[0]
+	if !reflect.DeepEqual(nested.Stats, &Stats{Count: 3, Strings: 3, Values: map[string]int{"a": 1, "b": 2}}) {
+		t.Errorf("Expected stats of nested field to be merged, Got: %+v", nested.Stats)
+	}
+}
+
+func TestEnumFormat(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.enum = true
+		c.enumMax = 3
+		c.enumSamples = 3
+		c.enumStrict = false
+	})()
+
+	sources := []string{
+		// Too few samples.
+		`["active", "suspended"]`,
+		// Too many distinct values.
+		`["a", "b", "c", "d"]`,
+	}
+
+	for _, source := range sources {
+		tree, err := Parse(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		TreeTestCase{"type _ []string\n", tree}.TestFormat(t)
+	}
+
+	tree, err := Parse(`[{"status": "active"}, {"status": "in-progress"}, {"status": ""}, {"status": "active"}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "type _ []struct {\n" +
+		"\tStatus Status `json:\"status\"`\n" +
+		"}\n\n" +
+		"type Status string\n\n" +
+		"const (\n" +
+		"\tStatusEmpty      Status = \"\"\n" +
+		"\tStatusActive     Status = \"active\"\n" +
+		"\tStatusInProgress Status = \"in-progress\"\n" +
+		")\n"
+
+	formatted, err := tree.Format()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(formatted) != expected {
+		t.Errorf("Expected: %q Got: %q", expected, formatted)
+	}
+}
+
+// Constants and types share names, so neither is declared twice.
+func TestEnumConstantNames(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.titleCase = true
+		c.enum = true
+		c.enumMax = 3
+		c.enumSamples = 1
+	})()
+
+	tree, err := Parse(`{"status": "active", "status_active": "yes"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "type _ struct {\n" +
+		"\tStatus       Status        `json:\"status\"`\n" +
+		"\tStatusActive StatusActive2 `json:\"status_active\"`\n" +
+		"}\n\n" +
+		"type Status string\n\n" +
+		"const (\n" +
+		"\tStatusActive Status = \"active\"\n" +
+		")\n\n" +
+		"type StatusActive2 string\n\n" +
+		"const (\n" +
+		"\tStatusActive2Yes StatusActive2 = \"yes\"\n" +
+		")\n"
+
+	formatted, err := tree.Format()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(formatted) != expected {
+		t.Errorf("Expected: %q Got: %q", expected, formatted)
+	}
+	vet(t, formatted)
+}
+
+func TestEnumStrict(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.enum = true
+		c.enumMax = 3
+		c.enumSamples = 1
+		c.enumStrict = true
+	})()
+
+	tree, err := Parse(`{"status": "active", "state": "active"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "import (\n" +
+		"\t\"encoding/json\"\n" +
+		"\t\"fmt\"\n" +
+		")\n\n" +
+		"type _ struct {\n" +
+		"\tState  State  `json:\"state\"`\n" +
+		"\tStatus Status `json:\"status\"`\n" +
+		"}\n\n" +
+		"type State string\n\n" +
+		"const (\n" +
+		"\tStateActive State = \"active\"\n" +
+		")\n\n" +
+		"func (v *State) UnmarshalJSON(data []byte) error {\n" +
+		"\tvar s string\n" +
+		"\tif err := json.Unmarshal(data, &s); err != nil {\n" +
+		"\t\treturn err\n" +
+		"\t}\n\n" +
+		"\tswitch State(s) {\n" +
+		"\tcase StateActive:\n" +
+		"\t\t*v = State(s)\n" +
+		"\t\treturn nil\n" +
+		"\t}\n\n" +
+		"\treturn fmt.Errorf(\"unknown State %q\", s)\n" +
+		"}\n\n" +
+		"type Status string\n\n" +
+		"const (\n" +
+		"\tStatusActive Status = \"active\"\n" +
+		")\n\n" +
+		"func (v *Status) UnmarshalJSON(data []byte) error {\n" +
+		"\tvar s string\n" +
+		"\tif err := json.Unmarshal(data, &s); err != nil {\n" +
+		"\t\treturn err\n" +
+		"\t}\n\n" +
+		"\tswitch Status(s) {\n" +
+		"\tcase StatusActive:\n" +
+		"\t\t*v = Status(s)\n" +
+		"\t\treturn nil\n" +
+		"\t}\n\n" +
+		"\treturn fmt.Errorf(\"unknown Status %q\", s)\n" +
+		"}\n"
+
+	formatted, err := tree.Format()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(formatted) != expected {
+		t.Errorf("Expected: %q Got: %q", expected, formatted)
+	}
+}
+
+func TestEnumNames(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.enum = true
+		c.enumMax = 3
+		c.enumSamples = 1
+		c.enumStrict = false
+	})()
+
+	tree, err := Parse(`{"a": {"kind": "x"}, "b": {"kind": "1"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "type _ struct {\n" +
+		"\tA struct {\n" +
+		"\t\tKind Kind `json:\"kind\"`\n" +
+		"\t} `json:\"a\"`\n" +
+		"\tB struct {\n" +
+		"\t\tKind Kind2 `json:\"kind\"`\n" +
+		"\t} `json:\"b\"`\n" +
+		"}\n\n" +
+		"type Kind string\n\n" +
+		"const (\n" +
+		"\tKindX Kind = \"x\"\n" +
+		")\n\n" +
+		"type Kind2 string\n\n" +
+		"const (\n" +
+		"\tKind21 Kind2 = \"1\"\n" +
+		")\n"
+
+	formatted, err := tree.Format()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(formatted) != expected {
+		t.Errorf("Expected: %q Got: %q", expected, formatted)
+	}
+}
+
+func TestEnumUpdate(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.enum = true
+		c.enumMax = 3
+		c.enumSamples = 1
+		c.enumStrict = true
+	})()
+
+	tree, err := Parse(`{"id": 1, "status": "active"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source := "package foo\n\nimport \"fmt\"\n\ntype Foo struct {\n\tID int64 `json:\"id\"`\n}\n"
+	updated, _, err := Update("test.go", []byte(source), "", &tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "package foo\n\n" +
+		"import \"encoding/json\"\n\n" +
+		"import \"fmt\"\n\n" +
+		"type Foo struct {\n" +
+		"\tID     int64  `json:\"id\"`\n" +
+		"\tStatus Status `json:\"status\"`\n" +
+		"}\n\n" +
+		"type Status string\n"
+
+	if len(updated) < len(expected) || string(updated[:len(expected)]) != expected {
+		t.Errorf("Expected prefix: %q Got: %q", expected, updated)
+	}
+}
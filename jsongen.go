@@ -18,12 +18,16 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"go/format"
+	"go/ast"
+	"go/token"
 	"log"
 	"os"
+	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"unicode"
 )
@@ -33,29 +37,226 @@ var config Config
 type Config struct {
 	dumpFilename string
 
-	dumpFile  *os.File
-	inputFile *os.File
+	dumpFile *os.File
+
+	// Files to infer from, standard input if empty.
+	inputFilenames []string
 
 	titleCase bool
 	normalize bool
+	stream    bool
+	jobs      int
+
+	enum        bool
+	enumMax     int
+	enumSamples int
+	enumStrict  bool
+
+	narrow     bool
+	narrowBits int
+	big        string
+
+	// Paths are true if quoted values are enabled for them and false if
+	// they're disabled, otherwise quoted sets the default.
+	quoted      bool
+	quotedPaths map[string]bool
+
+	base64    bool
+	base64Min int
+
+	tuples   bool
+	tupleMax int
+
+	unions   bool
+	unionKey string
+	unionMax int
+
+	recursive bool
+	raw       bool
+
+	// Enabled detectors and the types configured for them.
+	detectors     map[string]bool
+	detectorTypes map[string]string
+
+	// Command given after the flags, empty when generating a new type.
+	command string
+
+	updateFilename string
+	updateType     string
+
+	treeFilename  string
+	treeType      string
+	validateFiles []string
+
+	sampleOptional bool
+	sampleLength   int
+
+	// Name of the top level Go type and the file to write a round trip
+	// test of it to.
+	name         string
+	testFilename string
+
+	// Whether a Decode function is declared for the top level type, and how
+	// constraints observed of fields' values are declared.
+	decode     bool
+	validation string
+
+	// Language of the generated declarations, and the dialect of SQL.
+	lang       string
+	sqlDialect string
+
+	// Annotations of fields' doc comments and the maximum length of
+	// examples.
+	doc           map[string]bool
+	docExampleMax int
 }
 
 func (c *Config) Parse() (err error) {
 	flag.StringVar(&config.dumpFilename, "dump", os.DevNull, "Dump tree structure to file.")
 	flag.BoolVar(&config.normalize, "normalize", true, "Squash arrays of struct and determine primitive array type.")
 	flag.BoolVar(&config.titleCase, "title", true, "Convert identifiers to title case, treating '_' and '-' as word boundaries.")
+	flag.BoolVar(&config.stream, "stream", false, "Infer types while reading input instead of decoding it all first, always normalizes.")
+	flag.IntVar(&config.jobs, "j", runtime.NumCPU(), "Number of input files to infer from concurrently.")
+	flag.BoolVar(&config.enum, "enum", false, "Declare string types with constants for fields with few distinct values.")
+	flag.IntVar(&config.enumMax, "enum-max", 10, "Maximum number of distinct values of an enum.")
+	flag.IntVar(&config.enumSamples, "enum-samples", 3, "Minimum number of values observed of an enum.")
+	flag.BoolVar(&config.enumStrict, "enum-strict", false, "Declare UnmarshalJSON methods rejecting unknown enum values.")
+	flag.BoolVar(&config.narrow, "narrow", false, "Use the narrowest integer type which fits the values observed, unsigned if none are negative.")
+	flag.IntVar(&config.narrowBits, "narrow-bits", 8, "Minimum width of narrowed integer types.")
+	flag.StringVar(&config.big, "big", "float64", "Type of integers which don't fit in 64 bits: float64, number (json.Number) or bigint (*big.Int).")
+	flag.BoolVar(&config.quoted, "quoted", false, "Use numeric and bool types with the ,string tag option for strings which only hold numbers or bools.")
+	quotedPaths := flag.String("quoted-paths", "", "Comma separated paths of fields to enable -quoted for, e.g.: .id,.items.amount. Paths prefixed with - disable it.")
+	flag.BoolVar(&config.base64, "base64", false, "Use []byte for strings which are all base64 encoded.")
+	flag.IntVar(&config.base64Min, "base64-min", 16, "Minimum length of base64 encoded strings.")
+	flag.BoolVar(&config.tuples, "tuples", false, "Declare structs for short arrays with a consistent type at each position, e.g.: [\"EUR\", 12.5].")
+	flag.IntVar(&config.tupleMax, "tuple-max", 8, "Maximum number of elements of a tuple.")
+	flag.BoolVar(&config.unions, "unions", false, "Declare interfaces with a struct for each variant of lists of objects whose shape depends on a discriminator field.")
+	flag.StringVar(&config.unionKey, "union-key", "", "Discriminator field of unions, defaults to the first of: "+strings.Join(discriminators, ", ")+".")
+	flag.IntVar(&config.unionMax, "union-max", 16, "Maximum number of variants of a union.")
+	flag.BoolVar(&config.raw, "raw", false, "Use json.RawMessage instead of interface{} for values of conflicting or unknown types.")
+	flag.BoolVar(&config.recursive, "recursive", false, "Declare named types for objects nested in objects of the same shape, e.g.: Children []Category.")
+	flag.StringVar(&config.lang, "lang", "go", "Language of the generated declarations: "+emitterNames()+".")
+	flag.StringVar(&config.name, "name", "", "Name of the top level type, _ in Go and Root in other languages if empty.")
+	flag.StringVar(&config.testFilename, "test", "", "Write a test to file checking each input decodes into the top level type and encodes back to equal JSON, the type is named Root unless -name is given.")
+	flag.BoolVar(&config.decode, "decode", false, "Declare a Decode function for the top level type only, rejecting unknown fields and missing fields which were present in every sample.")
+	flag.StringVar(&config.validation, "validation", "", "Declare constraints observed of fields' values as validate tags or a Validate method of the top level type: tags, method.")
+	flag.StringVar(&config.sqlDialect, "sql-dialect", "postgres", "Dialect of the tables generated with -lang=sql: "+sqlDialectNames()+".")
+	doc := flag.String("doc", "", "Comma separated annotations of fields' doc comments: "+strings.Join(annotations, ", ")+".")
+	flag.IntVar(&config.docExampleMax, "doc-example-max", 32, "Maximum length of example strings in doc comments, longer ones are truncated.")
+	detectors := flag.String("detect", "", "Comma separated detectors to classify strings with: "+detectorNames()+".")
+	detectorTypes := flag.String("detect-type", "", "Comma separated types for detectors, e.g.: uuid=github.com/google/uuid.UUID,url=string.")
 
 	flag.Parse()
 
-	if flag.NArg() == 0 {
-		config.inputFile = os.Stdin
-	} else {
-		config.inputFile, err = os.Open(flag.Arg(0))
-		if err != nil {
-			return
+	switch c.big {
+	case "float64", "number", "bigint":
+	default:
+		return fmt.Errorf("unknown type for big integers: %q", c.big)
+	}
+
+	if _, ok := emitters[c.lang]; !ok {
+		return fmt.Errorf("unknown language: %q", c.lang)
+	}
+
+	if _, ok := sqlDialects[c.sqlDialect]; !ok {
+		return fmt.Errorf("unknown SQL dialect: %q", c.sqlDialect)
+	}
+
+	if err = c.parseDetectors(*detectors, *detectorTypes); err != nil {
+		return
+	}
+
+	if err = c.parseDoc(*doc); err != nil {
+		return
+	}
+
+	c.quotedPaths = make(map[string]bool)
+	for _, path := range strings.Split(*quotedPaths, ",") {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+		if strings.HasPrefix(path, "-") {
+			c.quotedPaths[path[1:]] = false
+		} else {
+			c.quotedPaths[path] = true
 		}
 	}
 
+	args := flag.Args()
+	if len(args) > 0 && args[0] == "update" {
+		c.command = args[0]
+
+		updateFlags := flag.NewFlagSet("update", flag.ExitOnError)
+		updateFlags.StringVar(&c.updateType, "type", "", "Name of the type to update, defaults to the first struct type in the file.")
+		updateFlags.Parse(args[1:])
+
+		args = updateFlags.Args()
+		if len(args) == 0 {
+			return errors.New("update: no Go source file given")
+		}
+		c.updateFilename, args = args[0], args[1:]
+	}
+
+	if len(args) > 0 && args[0] == "validate" {
+		c.command = args[0]
+
+		validateFlags := flag.NewFlagSet("validate", flag.ExitOnError)
+		validateFlags.StringVar(&c.treeFilename, "tree", "", "Load the tree from a dump, or a struct type in a Go file or package directory, instead of inferring it from a sample.")
+		validateFlags.StringVar(&c.treeType, "type", "", "Name of the struct type to load from Go source, defaults to the first struct type.")
+		validateFlags.Parse(args[1:])
+
+		// Without a dumped tree the first file is the sample to infer from.
+		args = validateFlags.Args()
+		if c.treeFilename == "" {
+			if len(args) == 0 {
+				return errors.New("validate: no sample given")
+			}
+			c.validateFiles = args[1:]
+			args = args[:1]
+		} else {
+			c.validateFiles = args
+			args = nil
+		}
+
+		if len(c.validateFiles) == 0 {
+			return errors.New("validate: no files given")
+		}
+	}
+
+	if len(args) > 0 && args[0] == "sample" {
+		c.command = args[0]
+
+		sampleFlags := flag.NewFlagSet("sample", flag.ExitOnError)
+		sampleFlags.StringVar(&c.treeFilename, "tree", "", "Load the tree from a dump, or a struct type in a Go file or package directory, instead of inferring it from inputs.")
+		sampleFlags.StringVar(&c.treeType, "type", "", "Name of the struct type to load from Go source, defaults to the first struct type.")
+		sampleFlags.BoolVar(&c.sampleOptional, "optional", true, "Include fields which were missing from some objects.")
+		sampleFlags.IntVar(&c.sampleLength, "length", 2, "Number of elements of lists.")
+		sampleFlags.Parse(args[1:])
+
+		args = sampleFlags.Args()
+		if c.treeFilename != "" && len(args) > 0 {
+			return errors.New("sample: both a tree and inputs given")
+		}
+	}
+
+	switch c.validation {
+	case "", "tags", "method":
+	default:
+		return fmt.Errorf("unknown validation: %q", c.validation)
+	}
+
+	if c.testFilename != "" || c.decode || c.validation != "" {
+		if c.lang != "go" || c.command != "" {
+			return errors.New("-test, -decode and -validation are only supported when generating Go types")
+		}
+		// Functions and methods of the top level type require a name.
+		if c.name == "" && (c.testFilename != "" || c.decode || c.validation == "method") {
+			c.name = "Root"
+		}
+	}
+
+	c.inputFilenames = args
+
 	c.dumpFile, err = os.Create(c.dumpFilename)
 	if err != nil {
 		return
@@ -66,7 +267,6 @@ func (c *Config) Parse() (err error) {
 
 func (c Config) Close() {
 	c.dumpFile.Close()
-	c.inputFile.Close()
 }
 
 // Field name sanitizer.
@@ -120,9 +320,32 @@ func (id Ident) String() (s string) {
 	return
 }
 
-// Returns a field tag for the original field name.
-func (id Ident) Tag() string {
-	return "`json:\"" + string(id) + "\"`"
+// Returns a field tag for the original field name with any options given,
+// as a string literal.
+func (id Ident) Tag(options ...string) string {
+	return quoteTag(id.jsonTag(options...))
+}
+
+// Returns the json key of a field tag for the original field name.
+func (id Ident) jsonTag(options ...string) string {
+	return "json:" + strconv.Quote(strings.Join(append([]string{string(id)}, options...), ","))
+}
+
+// Returns a field tag as a string literal, raw unless it holds a backtick.
+func quoteTag(tag string) string {
+	if strings.Contains(tag, "`") {
+		return strconv.Quote(tag)
+	}
+	return "`" + tag + "`"
+}
+
+// Appends a field name to a JSON path, paths are written as .a.b with . being
+// the document itself.
+func joinPath(path, name string) string {
+	if path == "." {
+		return path + name
+	}
+	return path + "." + name
 }
 
 // JSON values are translated to go types as follows:
@@ -132,6 +355,10 @@ func (id Ident) Tag() string {
 // float  -> float64
 // string -> string
 // object -> struct
+// array  -> tuple, a struct with a field for each position
+// list of objects with a discriminator -> union, a struct for each variant
+// object nested in an object of the same shape -> recursive, the named type
+// of the outer object
 type Type int
 
 const (
@@ -141,6 +368,9 @@ const (
 	Float
 	String
 	Struct
+	Tuple
+	Union
+	Recursive
 )
 
 func (t Type) String() string {
@@ -157,6 +387,12 @@ func (t Type) String() string {
 		return "string"
 	case Struct:
 		return "struct"
+	case Tuple:
+		return "tuple"
+	case Union:
+		return "union"
+	case Recursive:
+		return "recursive"
 	}
 	return "unset"
 }
@@ -166,13 +402,36 @@ func (t Type) MarshalText() (text []byte, err error) {
 	return []byte(t.String()), nil
 }
 
+// Necessary for loading a dumped tree.
+func (t *Type) UnmarshalText(text []byte) error {
+	for typ := Interface; typ <= Recursive; typ++ {
+		if typ.String() == string(text) {
+			*t = typ
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown type %q", text)
+}
+
 // A type tree describes parsed JSON input. Elements have a name, type and
-// children, list specifies if the type is a list.
+// children, list specifies if the type is a list. The children of a tuple are
+// its positions, named by index. The children of a union are its variants,
+// named by the value of the discriminator field. Recursive types refer to the
+// path of the struct they share a shape with. Stats are only gathered if an
+// option requires them. Trees loaded from Go source mark fields which may be
+// missing as optional and fields which may be null as nullable, and name the
+// detector matching strings of types with a format, e.g.: time for time.Time.
 type Tree struct {
-	Name     Ident `json:",omitempty"`
-	List     bool  `json:",omitempty"`
-	Type     Type
-	Children []*Tree `json:",omitempty"`
+	Name          Ident `json:",omitempty"`
+	List          bool  `json:",omitempty"`
+	Type          Type
+	Children      []*Tree `json:",omitempty"`
+	Discriminator Ident   `json:",omitempty"`
+	Ref           string  `json:",omitempty"`
+	Optional      bool    `json:",omitempty"`
+	Nullable      bool    `json:",omitempty"`
+	Detector      string  `json:",omitempty"`
+	Stats         *Stats  `json:",omitempty"`
 }
 
 // A tree implements the sort interface on it's children's sanitized names.
@@ -181,69 +440,332 @@ func (t Tree) Len() int {
 }
 
 func (t Tree) Less(i, j int) bool {
-	return t.Children[i].Name.String() < t.Children[j].Name.String()
+	// Fall back on original names for identical sanitized names.
+	a, b := t.Children[i].Name.String(), t.Children[j].Name.String()
+	if a == b {
+		return t.Children[i].Name < t.Children[j].Name
+	}
+	return a < b
 }
 
 func (t Tree) Swap(i, j int) {
 	t.Children[i], t.Children[j] = t.Children[j], t.Children[i]
 }
 
-// Returns canonical golang of the type structure.
-func (t *Tree) Format() (formatted []byte, err error) {
-	// Store the raw source for debugging.
-	unformatted := []byte("type " + t.formatHelper(0))
+// Names declared in a scope, such as the types of a file or the fields of a
+// struct. Every language's declarations are named with one, so names taken
+// are numbered the same way.
+type namer struct {
+	taken map[string]bool
 
-	// Attempt to format the source.
-	formatted, err = format.Source(unformatted)
+	// Separates names from the number of a name which was taken.
+	separator string
+}
 
-	// If formatting failed, return the unformatted source and the error.
-	if err != nil {
-		formatted = unformatted
+func newNamer(separator string) *namer {
+	return &namer{taken: make(map[string]bool), separator: separator}
+}
+
+// Reserves a name, numbering it if it is already taken. The blank identifier
+// may be declared any number of times.
+func (n *namer) declare(name string) string {
+	unique := name
+	for idx := 2; name != "_" && n.taken[unique]; idx++ {
+		unique = name + n.separator + strconv.Itoa(idx)
+	}
+	n.taken[unique] = true
+	return unique
+}
+
+// Collects the imports and additional declarations required by a type while
+// it is formatted.
+type formatter struct {
+	// Names of declared types.
+	names   *namer
+	imports map[string]bool
+	decls   []string
+
+	// Path of the field being formatted.
+	path string
+
+	// Notes about types detected while formatting.
+	diagnostics []string
+
+	// Lines of the doc comment of the field being formatted.
+	doc []string
+
+	// Fields of tuples, which are decoded by position rather than by tag.
+	positions map[*Tree]bool
+
+	// Names of structs recursive types refer to by path, empty until the
+	// struct is declared.
+	refs map[string]string
+
+	// Names of the types declared for detectors, by detector.
+	detectorTypes map[string]string
+
+	// Constraints of fields by path, checked by the Validate method.
+	checks map[string]constraints
+
+	// Lines of the declaration being built, see lines.
+	fset  *token.FileSet
+	file  *token.File
+	lines *lines
+
+	// First error printing declarations.
+	err error
+}
+
+func newFormatter() *formatter {
+	return &formatter{names: newNamer(""), imports: make(map[string]bool), path: ".", positions: make(map[*Tree]bool), refs: make(map[string]string), detectorTypes: make(map[string]string), checks: make(map[string]constraints), fset: token.NewFileSet()}
+}
+
+// Returns the import declaration and given declaration followed by any
+// additional declarations.
+func (f *formatter) source(decl string) string {
+	var paths []string
+	for path := range f.imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var r string
+	if len(paths) > 0 {
+		l := f.begin()
+		defer f.end(l)
+		imports := &ast.GenDecl{TokPos: l.line(), Tok: token.IMPORT}
+		if len(paths) > 1 {
+			imports.Lparen = l.pos()
+		}
+		for _, path := range paths {
+			pos := l.pos()
+			if len(paths) > 1 {
+				pos = l.line()
+			}
+			imports.Specs = append(imports.Specs, &ast.ImportSpec{Path: &ast.BasicLit{ValuePos: pos, Kind: token.STRING, Value: strconv.Quote(path)}})
+		}
+		if len(paths) > 1 {
+			imports.Rparen = l.line()
+		}
+		r += f.print(imports, l) + "\n"
+	}
+
+	r += decl
+	for _, d := range f.decls {
+		r += "\n" + d
+	}
+	return r
+}
+
+// Returns the type of a field and any options its tag requires.
+func (f *formatter) fieldType(t *Tree, depth int) (name string, options []string) {
+	// Tag options only apply to fields.
+	if depth > 0 && !f.positions[t] {
+		if name, ok := f.quoted(t); ok {
+			return name, []string{"string"}
+		}
+	}
+	return f.typeName(t), nil
+}
+
+// Records a note about the field being formatted.
+func (f *formatter) note(format string, args ...interface{}) {
+	f.diagnostics = append(f.diagnostics, f.path+": "+fmt.Sprintf(format, args...))
+}
+
+// Returns the type of a tree's values, declaring any named types required.
+func (f *formatter) typeName(t *Tree) string {
+	if t.Type == Tuple {
+		return f.tuple(t)
+	}
+	if t.Type == Union {
+		return f.union(t)
 	}
+	if t.Type == Recursive {
+		return f.recursive(t)
+	}
+	if _, ok := f.refs[f.path]; ok && t.Type == Struct {
+		return f.referenced(t)
+	}
+	if t.base64() {
+		f.note("base64 encoded, declared as []byte")
+		return "[]byte"
+	}
+	if name, ok := f.detected(t); ok {
+		return name
+	}
+	if name, ok := f.enum(t); ok {
+		return name
+	}
+	if t.Type == Int {
+		return f.integer(t)
+	}
+	if t.Type == Interface {
+		return f.unknown()
+	}
+	return t.Type.String()
+}
+
+// Returns the type of values of conflicting or unknown types, which are left
+// undecoded if config.raw is set.
+func (f *formatter) unknown() string {
+	if config.raw {
+		f.imports["encoding/json"] = true
+		return "json.RawMessage"
+	}
+	return Interface.String()
+}
+
+// Returns canonical golang of the type structure.
+func (t *Tree) Format() (formatted []byte, err error) {
+	formatted, _, err = t.FormatDiagnostics()
 	return
 }
 
-func (t *Tree) formatHelper(depth int) (r string) {
-	indent := strings.Repeat("\t", depth)
+// Returns canonical golang of the type structure and notes about types
+// detected while formatting. Type declarations are built as syntax trees and
+// printed. Functions and methods, such as those of enums, tuples, unions and
+// the top level type, are executed from templates and parsed, an error is
+// returned if one can't be.
+func (t *Tree) FormatDiagnostics() (formatted []byte, diagnostics []string, err error) {
+	f := newFormatter()
+	f.names.declare(t.Name.String())
+	f.reference(t)
+
+	decl := t.formatDecl(f)
+	// Functions and methods of the top level type follow its declaration.
+	var funcs []string
+	if config.decode {
+		funcs = append(funcs, f.decoder(t))
+	}
+	if config.validation == "method" {
+		funcs = append(funcs, f.validator(t))
+	}
+	f.decls = append(funcs, f.decls...)
+
+	formatted = []byte(f.source(decl))
+	return formatted, f.diagnostics, f.err
+}
 
-	// Print the name of the current element.
-	r += indent + t.Name.String() + " "
+// Returns the type declaration of a tree, named after it.
+func (t *Tree) formatDecl(f *formatter) string {
+	typ, _ := f.fieldType(t, 0)
 
-	// On return append a tag if the field name differs from the parsed name.
+	l := f.begin()
+	defer f.end(l)
+
+	doc := f.comment()
+	pos := l.line()
+	spec := &ast.TypeSpec{Name: &ast.Ident{NamePos: l.pos(), Name: t.Name.String()}, Type: f.typeExpr(t, typ)}
+	return f.print(&ast.GenDecl{Doc: doc, TokPos: pos, Tok: token.TYPE, Specs: []ast.Spec{spec}}, l)
+}
+
+// Returns the declaration of a struct with the given name and the fields of
+// a tree, preceded by a doc comment if there is one.
+func (f *formatter) structDecl(name string, t *Tree, doc ...string) string {
+	l := f.begin()
+	defer f.end(l)
+
+	// The declaration is outside of the field being formatted.
+	outer := f.doc
 	defer func() {
-		if depth != 0 && string(t.Name) != t.Name.String() {
-			r += " " + t.Name.Tag()
-		}
-		r += "\n"
+		f.doc = outer
 	}()
 
-	// Prefix the type with [] if list is true.
-	if t.List {
-		r += "[]"
-	}
+	f.doc = doc
+	group := f.comment()
+	pos := l.line()
+	spec := &ast.TypeSpec{Name: &ast.Ident{NamePos: l.pos(), Name: name}, Type: f.structType(t)}
+	return f.print(&ast.GenDecl{Doc: group, TokPos: pos, Tok: token.TYPE, Specs: []ast.Spec{spec}}, l)
+}
 
-	// Print type
-	r += t.Type.String()
+// Returns a field of a struct: its name, type and a tag if the field name
+// differs from the parsed name or the tag has options.
+func (t *Tree) formatField(f *formatter, parent *Tree) *ast.Field {
+	// Keep track of the path of the current element.
+	path := f.path
+	f.path = joinPath(path, string(t.Name))
+	defer func() {
+		f.path = path
+	}()
 
-	// If the type is a struct, print struct and enclosing curly braces.
-	if t.Type == Struct {
-		r += " {\n"
-		defer func() {
-			r += indent + "}"
-		}()
+	typ, options := f.fieldType(t, 1)
+	if line := t.annotation(parent); line != "" {
+		f.doc = append(f.doc, line)
+	}
 
-		// Recurse for each child of the struct.
-		for _, child := range t.Children {
-			r += child.formatHelper(depth + 1)
+	field := &ast.Field{Doc: f.comment()}
+	field.Names = []*ast.Ident{{NamePos: f.lines.line(), Name: t.Name.String()}}
+	field.Type = f.typeExpr(t, typ)
+
+	// Constraints are either tags of the field or checked by the top level
+	// type's Validate method.
+	var validate string
+	if config.validation != "" {
+		c := f.constraints(t, parent, typ, options)
+		if config.validation == "tags" {
+			validate = c.tag(t)
+		} else {
+			f.checks[f.path] = c
 		}
+	}
+
+	if validate != "" {
+		field.Tag = &ast.BasicLit{ValuePos: f.lines.pos(), Kind: token.STRING, Value: quoteTag(t.Name.jsonTag(options...) + " validate:" + strconv.Quote(validate))}
+	} else if string(t.Name) != t.Name.String() || len(options) > 0 {
+		field.Tag = &ast.BasicLit{ValuePos: f.lines.pos(), Kind: token.STRING, Value: t.Name.Tag(options...)}
+	}
+	return field
+}
+
+// Returns the type of a tree given the name of the type of its values, an
+// anonymous struct if the name is struct.
+func (f *formatter) typeExpr(t *Tree, typ string) (expr ast.Expr) {
+	if typ == Struct.String() {
+		expr = f.structType(t)
 	} else {
+		expr = f.lines.parseType(typ)
 	}
 
+	if t.List {
+		expr = &ast.ArrayType{Elt: expr}
+	}
 	return
 }
 
+// Returns a struct type with a field for each child of the tree, each on a
+// line of its own.
+func (f *formatter) structType(t *Tree) *ast.StructType {
+	fields := &ast.FieldList{Opening: f.lines.pos()}
+	for _, child := range t.Children {
+		fields.List = append(fields.List, child.formatField(f, t))
+	}
+	fields.Closing = f.lines.line()
+	return &ast.StructType{Fields: fields}
+}
+
+// Returns the comment group of the lines of f.doc, nil if there are none.
+func (f *formatter) comment() *ast.CommentGroup {
+	if len(f.doc) == 0 {
+		return nil
+	}
+
+	group := &ast.CommentGroup{}
+	for _, line := range f.doc {
+		group.List = append(group.List, &ast.Comment{Slash: f.lines.line(), Text: "// " + line})
+	}
+	f.doc = nil
+
+	f.lines.comments = append(f.lines.comments, group)
+	return group
+}
+
 // Given a value which JSON has been parsed into, populates the tree.
 func (t *Tree) Populate(v interface{}) {
+	t.Stats = newStats()
+	t.Stats.observeKind(jsonKind(v))
+	t.Stats.observeExample(v)
+
 	// Handles null value in JSON.
 	if v == nil {
 		t.Type = Interface
@@ -255,10 +777,16 @@ func (t *Tree) Populate(v interface{}) {
 		t.Type = Bool
 	case string:
 		t.Type = String
+		t.Stats.observeString(i)
 	case json.Number:
-		// If number parses successfully as an int, store as int.
+		// If number parses successfully as an int, store as int. Integers
+		// too large for int64 are also stored as int if their range is
+		// tracked, the type is chosen from the range when formatting.
+		t.Stats.observeNumber(i)
 		if _, err := i.Int64(); err == nil {
 			t.Type = Int
+		} else if _, ok := integer(i); ok && t.Stats != nil {
+			t.Type = Int
 		} else {
 			// Float should always succeed in parsing so only store as float
 			// if parsing as int failed.
@@ -271,6 +799,7 @@ func (t *Tree) Populate(v interface{}) {
 		// later if normalization is used. Recurse for each child.
 		t.List = true
 		t.Type = Interface
+		t.Stats.observeLength(len(i))
 		for _, v := range i {
 			child := &Tree{}
 			child.Populate(v)
@@ -293,9 +822,14 @@ func (t *Tree) Populate(v interface{}) {
 // into one struct. If fields have conflicting types while squashing a
 // list of struct, the offending field is converted to the empty interface.
 func (t *Tree) Normalize() {
+	t.normalize(newSigner())
+	t.ResolveTuples()
+}
+
+func (t *Tree) normalize(s *signer) {
 	// Normalize from the bottom up so use depth first iteration.
 	for idx := range t.Children {
-		t.Children[idx].Normalize()
+		t.Children[idx].normalize(s)
 	}
 
 	// Normalization only applies to lists.
@@ -303,144 +837,342 @@ func (t *Tree) Normalize() {
 		return
 	}
 
-	// Make a map of children types.
-	types := make(map[Type]bool)
+	e := newElements(s, config.tuples)
 	for _, child := range t.Children {
-		types[child.Type] = true
+		e.add(child)
+	}
+	e.finish(t)
+}
+
+// Accumulates the elements of a list one at a time, only keeping what is
+// needed to determine the list's type: the set of element types, the
+// combined stats of the elements and, while every element is a struct, the
+// squashed fields of the elements or, while every element is a tuple, the
+// squashed positions of the elements.
+type elements struct {
+	signer *signer
+	types  map[Type]bool
+	fields map[Ident]*Tree
+	tuple  *Tree
+	stats  *Stats
+
+	// Struct elements squashed into a variant for each value of their
+	// discriminator key while they may be a union.
+	unions   bool
+	key      Ident
+	variants map[Ident]*Tree
+
+	// Short lists may be tuples, so their elements are kept as they are
+	// until there are more than config.tupleMax.
+	buffering bool
+	buffer    []*Tree
+}
+
+func newElements(s *signer, tuples bool) *elements {
+	return &elements{signer: s, types: make(map[Type]bool), fields: make(map[Ident]*Tree), buffering: tuples, unions: config.unions}
+}
+
+// Adds a normalized element to the list.
+func (e *elements) add(element *Tree) {
+	// Elements kept as tuple positions keep their own stats, so stats
+	// merged into are copied rather than shared.
+	if e.stats == nil {
+		e.stats = element.Stats.copy()
+	} else {
+		e.stats = mergeStats(e.stats, element.Stats)
+	}
+
+	if e.buffering {
+		e.buffer = append(e.buffer, element)
+		if len(e.buffer) <= config.tupleMax {
+			return
+		}
+		e.flush()
+		return
+	}
+
+	e.squash(element)
+}
+
+// Squashes any buffered elements, the list is too long to be a tuple.
+func (e *elements) flush() {
+	e.buffering = false
+	for _, element := range e.buffer {
+		e.squash(element)
+	}
+	e.buffer = nil
+}
+
+func (e *elements) squash(element *Tree) {
+	// Tuples are squashed position by position while every element is a
+	// tuple of the same shape, otherwise they're lists.
+	if element.Type == Tuple {
+		switch {
+		case len(e.types) == 0:
+			e.types[Tuple] = true
+			e.tuple = element
+			return
+		case e.tuple != nil && e.compatible(e.tuple, element):
+			e.mergeTuple(e.tuple, element)
+			return
+		}
+		e.demote(element)
+	}
+	if e.tuple != nil {
+		tuple := e.tuple
+		e.tuple = nil
+		e.types = make(map[Type]bool)
+		e.demote(tuple)
+		e.squash(tuple)
+	}
+
+	// Structs are grouped by their discriminator while they may be a union,
+	// unions which can't be are structs.
+	if e.unions && (element.Type == Struct || element.Type == Union) && e.variant(element) {
+		return
+	}
+	if element.Type == Union {
+		e.collapseUnion(element)
+	}
+
+	e.types[element.Type] = true
+
+	// Fields are only squashed if this is a list of structs.
+	if len(e.types) != 1 || !e.types[Struct] {
+		e.fields = nil
+		return
+	}
+
+	// For each grand-child.
+	for _, child := range element.Children {
+		// Store grand-child in fields map if it doesn't already exist.
+		field, exists := e.fields[child.Name]
+		if !exists {
+			e.fields[child.Name] = child
+			continue
+		}
+
+		// Merge the grand-child with the one already stored in fields. If
+		// their structures conflict, store as empty interface.
+		if !e.merge(field, child) {
+			field.Type = Interface
+			field.List = field.List && child.List
+			field.Children = nil
+			field.Stats = mergeStats(field.Stats, child.Stats)
+			e.signer.forget(field)
+		}
+
+		// The grand-child is discarded, so is its signature.
+		e.signer.forgetTree(child)
+	}
+}
+
+// Merges a tree into another describing the same field, returning false if
+// their structures conflict. Tuples of the same shape are merged position by
+// position, otherwise they're compared as lists.
+func (e *elements) merge(a, b *Tree) bool {
+	if (a.Type == Union || b.Type == Union) && e.mergeUnion(a, b) {
+		return true
+	}
+	if a.Type == Tuple && b.Type == Tuple && e.compatible(a, b) {
+		e.mergeTuple(a, b)
+		return true
+	}
+	if a.Type == Tuple {
+		e.demote(a)
+	}
+	if b.Type == Tuple {
+		e.demote(b)
+	}
+
+	if e.signer.sign(a) != e.signer.sign(b) {
+		return false
+	}
+	mergeTreeStats(a, b)
+	return true
+}
+
+// Sets the type, children and stats of the list from the elements added.
+func (e *elements) finish(t *Tree) {
+	// Remove all of the children.
+	t.Children = nil
+
+	if t.Stats != nil {
+		t.Stats.Elements = e.stats
+	}
+
+	// Lists of more than one element short enough to be tuples are, unless
+	// they turn out to have the same type at every position.
+	if e.buffering {
+		if len(e.buffer) > 1 {
+			t.List = false
+			t.Type = Tuple
+			for idx, element := range e.buffer {
+				e.signer.forget(element)
+				element.Name = Ident(strconv.Itoa(idx))
+				t.Children = append(t.Children, element)
+			}
+			return
+		}
+		e.flush()
 	}
 
-	switch len(types) {
+	// Structs whose variants differ in shape are a union.
+	if e.variants != nil && len(e.types) == 1 {
+		if e.distinct() {
+			t.Type = Union
+			t.Discriminator = e.key
+			for _, variant := range e.variants {
+				t.Children = append(t.Children, variant)
+			}
+			sort.Sort(t)
+			return
+		}
+		e.collapse()
+	}
+
+	switch len(e.types) {
 	// Children are all of the same type.
 	case 1:
 		// Get first key out of 1-element map.
-		for typ := range types {
+		for typ := range e.types {
 			t.Type = typ
 		}
 
-		// If this is a list of structs, squash grand-children into single struct.
-		if t.List && t.Type == Struct {
-			// Make a map of grand-children
-			fields := make(map[Ident]*Tree)
-
-			// For each child struct.
-			for _, element := range t.Children {
-				// For each grand-child.
-				for _, child := range element.Children {
-					// Store grand-child in fields map if it doesn't already exist.
-					if _, exists := fields[child.Name]; !exists {
-						fields[child.Name] = child
-					} else {
-						// Recursively compare the grand-child type with the
-						// one already stored in fields. If the comparison
-						// fails, store as empty interface.
-						if !Compare(fields[child.Name], child) {
-							fields[child.Name].Type = Interface
-							fields[child.Name].Children = nil
-						}
-					}
-				}
-			}
-
-			// Remove all of the children.
-			t.Children = nil
-
-			// Store squashed list of children.
-			for _, child := range fields {
+		// If this is a list of structs, store squashed list of children.
+		if t.Type == Struct {
+			for _, child := range e.fields {
 				t.Children = append(t.Children, child)
 			}
 
 			// Sort new list of children.
 			sort.Sort(t)
-		} else {
-			// Not a list of struct so just remove all children.
-			t.Children = nil
+		}
+
+		// If this is a list of tuples, store the squashed positions.
+		if t.Type == Tuple {
+			t.Children = e.tuple.Children
 		}
 	case 2:
 		// Two types found, store as float if both types are int and float.
-		if types[Int] && types[Float] {
+		if e.types[Int] && e.types[Float] {
 			t.Type = Float
-			t.Children = nil
+			break
 		}
+
+		// Otherwise the list is heterogeneous, store as empty interface.
+		t.Type = Interface
 	default:
 		// Heterogeneous list types, store as empty interface.
 		t.Type = Interface
-		t.Children = nil
 	}
 }
 
-// Used for comparing fields between structs while squashing a list of struct.
-type FieldType struct {
-	Name Ident
-	List bool
-	Type Type
+// Assigns trees with identical structure the same signature. A tree's
+// signature is derived from its name, list flag, type and the signatures of
+// its children, so each tree is only visited once regardless of how many
+// times it is compared.
+type signer struct {
+	// Signatures of each distinct structure seen.
+	ids map[string]int
+	// Signatures of trees already visited.
+	memo map[*Tree]int
 }
 
-// Recursively compares field names and types of two structs.
-func Compare(t1, t2 *Tree) bool {
-	// Spawn two walkers for each tree.
-	c1, c2 := Walker(t1), Walker(t2)
-	for {
-		// Get values from walkers.
-		v1, ok1 := <-c1
-		v2, ok2 := <-c2
-		// If either of the walkers closed their channel.
-		if !ok1 || !ok2 {
-			// Check to see if both closed at the same time.
-			return ok1 == ok2
-		}
-		// If the received values don't match, comparison fails.
-		if v1 != v2 {
-			break
-		}
+func newSigner() *signer {
+	return &signer{make(map[string]int), make(map[*Tree]int)}
+}
+
+// Returns the signature of a tree, trees must not be modified once signed
+// unless they are forgotten.
+func (s *signer) sign(t *Tree) int {
+	if id, ok := s.memo[t]; ok {
+		return id
+	}
+
+	key := make([]byte, 0, 32)
+	key = strconv.AppendQuote(key, string(t.Name))
+	key = strconv.AppendBool(key, t.List)
+	key = strconv.AppendInt(key, int64(t.Type), 10)
+	if t.Type == Union {
+		key = strconv.AppendQuote(key, string(t.Discriminator))
+	}
+	for _, child := range t.Children {
+		key = append(key, ',')
+		key = strconv.AppendInt(key, int64(s.sign(child)), 10)
 	}
-	return false
+
+	id, ok := s.ids[string(key)]
+	if !ok {
+		id = len(s.ids)
+		s.ids[string(key)] = id
+	}
+	s.memo[t] = id
+
+	return id
 }
 
-// Recursively walks a tree, returns a channel of values.
-func Walker(t *Tree) <-chan FieldType {
-	ch := make(chan FieldType)
-	go func() {
-		Walk(t, ch)
-		close(ch)
-	}()
-	return ch
+// Discards the signature of a modified tree.
+func (s *signer) forget(t *Tree) {
+	delete(s.memo, t)
 }
 
-// Recursively walks a tree, sending values on given channel.
-func Walk(t *Tree, ch chan FieldType) {
-	if t == nil {
+// Discards the signatures of a tree and its descendants so discarded trees
+// can be garbage collected.
+func (s *signer) forgetTree(t *Tree) {
+	if _, ok := s.memo[t]; !ok {
 		return
 	}
 
-	ch <- FieldType{t.Name, t.List, t.Type}
+	delete(s.memo, t)
 	for _, child := range t.Children {
-		Walk(child, ch)
+		s.forgetTree(child)
 	}
 }
 
+// Recursively compares field names and types of two structs.
+func Compare(t1, t2 *Tree) bool {
+	s := newSigner()
+	return s.sign(t1) == s.sign(t2)
+}
+
 func init() {
 	log.SetFlags(log.Lshortfile)
+}
 
+func main() {
 	if err := config.Parse(); err != nil {
 		log.Fatal("Error parsing flags:", err)
 	}
-}
-
-func main() {
 	defer config.Close()
 
-	jsonDecoder := json.NewDecoder(config.inputFile)
-	jsonDecoder.UseNumber()
-	var data interface{}
-	err := jsonDecoder.Decode(&data)
-	if err != nil {
-		log.Fatal("Error decoding input: ", err)
+	var tree Tree
+	var samples []string
+	if config.treeFilename != "" {
+		load := LoadTree
+		if isSource(config.treeFilename) {
+			load = func(filename string, t *Tree) error {
+				return LoadSource(filename, config.treeType, t)
+			}
+		}
+		if err := load(config.treeFilename, &tree); err != nil {
+			log.Fatal("Error loading tree: ", err)
+		}
+	} else {
+		if config.testFilename != "" {
+			read, err := readSamples(config.inputFilenames)
+			if err != nil {
+				log.Fatal("Error reading input: ", err)
+			}
+			samples = read
+		}
+		if err := InferFiles(&tree, config.inputFilenames, config.jobs); err != nil {
+			log.Fatal("Error decoding input: ", err)
+		}
 	}
 
-	var tree Tree
-	tree.Populate(data)
-	if config.normalize {
-		tree.Normalize()
+	if config.recursive {
+		tree.DetectRecursion()
 	}
 
 	indented, err := json.MarshalIndent(tree, "", "\t")
@@ -453,9 +1185,37 @@ func main() {
 		log.Fatal("Error dumping tree:", err)
 	}
 
-	source, err := tree.Format()
+	switch config.command {
+	case "update":
+		updateType(&tree)
+		return
+	case "validate":
+		if !validateFiles(&tree, config.validateFiles) {
+			os.Exit(1)
+		}
+		return
+	case "sample":
+		sample, err := json.MarshalIndent(tree.Sample(config.sampleOptional, config.sampleLength), "", "\t")
+		if err != nil {
+			log.Fatal("Error encoding sample:", err)
+		}
+		fmt.Println(string(sample))
+		return
+	}
+
+	tree.Name = Ident(config.name)
+	source, diagnostics, err := emitters[config.lang].Emit(tree.Graph())
+	for _, diagnostic := range diagnostics {
+		fmt.Fprintln(os.Stderr, diagnostic)
+	}
 	fmt.Println(string(source))
 	if err != nil {
 		log.Fatal("Error formatting source:", err)
 	}
+
+	if config.testFilename != "" {
+		if err := writeTest(config.testFilename, tree.Name.String(), samples); err != nil {
+			log.Fatal("Error writing test: ", err)
+		}
+	}
 }
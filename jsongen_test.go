@@ -42,6 +42,14 @@ func Parse(raw string) (tree Tree, err error) {
 	return
 }
 
+// Changes the config for the duration of a test, returning a function which
+// restores it.
+func withConfig(change func(c *Config)) func() {
+	saved := config
+	change(&config)
+	return func() { config = saved }
+}
+
 type TreeTestCase struct {
 	Source string
 	Tree   Tree
@@ -186,6 +194,8 @@ func TestStringList(t *testing.T) {
 func TestHeterogeneousList(t *testing.T) {
 	testCases := []TreeTestCase{
 		{`[true, false, 0, 1, 0.0, 1.0, "", "foo"]`, Tree{Type: Interface, List: true}},
+		{`[true, "foo"]`, Tree{Type: Interface, List: true}},
+		{`[null, 1.0]`, Tree{Type: Interface, List: true}},
 	}
 
 	for _, testCase := range testCases {
@@ -386,3 +396,114 @@ func TestSanitizier(t *testing.T) {
 		}
 	}
 }
+
+func TestStructNestedConflict(t *testing.T) {
+	testCases := []TreeTestCase{
+		{`[
+			{"struct": {"int": 1, "list": [{"bool": true}]}},
+			{"struct": {"int": 2, "list": [{"bool": false}]}}
+		]`,
+			Tree{Type: Struct, List: true, Children: []*Tree{
+				{Name: "struct", Type: Struct, Children: []*Tree{
+					{Name: "int", Type: Int},
+					{Name: "list", Type: Struct, List: true, Children: []*Tree{
+						{Name: "bool", Type: Bool},
+					}},
+				}},
+			}},
+		},
+		{`[
+			{"struct": {"int": 1, "list": [{"bool": true}]}},
+			{"struct": {"int": 2, "list": [{"bool": "false"}]}},
+			{"struct": {"int": 3, "list": [{"bool": true}]}}
+		]`,
+			Tree{Type: Struct, List: true, Children: []*Tree{
+				{Name: "struct", Type: Interface},
+			}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase.TestTree(t)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	testCases := []struct {
+		A, B  Tree
+		Equal bool
+	}{
+		{Tree{Type: Int}, Tree{Type: Int}, true},
+		{Tree{Type: Int}, Tree{Type: Float}, false},
+		{Tree{Type: Int}, Tree{Type: Int, List: true}, false},
+		{Tree{Name: "a", Type: Int}, Tree{Name: "b", Type: Int}, false},
+		{
+			Tree{Type: Struct, Children: []*Tree{{Name: "a", Type: Int}, {Name: "b", Type: Bool}}},
+			Tree{Type: Struct, Children: []*Tree{{Name: "a", Type: Int}, {Name: "b", Type: Bool}}},
+			true,
+		},
+		{
+			Tree{Type: Struct, Children: []*Tree{{Name: "a", Type: Int}, {Name: "b", Type: Bool}}},
+			Tree{Type: Struct, Children: []*Tree{{Name: "a", Type: Int}}},
+			false,
+		},
+		{
+			Tree{Type: Struct, Children: []*Tree{{Name: "a", Type: Struct, Children: []*Tree{{Name: "b", Type: Bool}}}}},
+			Tree{Type: Struct, Children: []*Tree{{Name: "a", Type: Struct}, {Name: "b", Type: Bool}}},
+			false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		if Compare(&testCase.A, &testCase.B) != testCase.Equal {
+			t.Errorf("A: %+v B: %+v Expected: %t", testCase.A, testCase.B, testCase.Equal)
+		}
+	}
+}
+
+// Builds a list of n structs for benchmarking, every element has the same
+// fields so nothing conflicts and every field is compared.
+func benchmarkList(n int) []interface{} {
+	list := make([]interface{}, n)
+	for idx := range list {
+		list[idx] = map[string]interface{}{
+			"bool":   true,
+			"int":    json.Number("1"),
+			"float":  json.Number("1.5"),
+			"string": "foo",
+			"struct": map[string]interface{}{
+				"int":  json.Number("1"),
+				"list": []interface{}{json.Number("1"), json.Number("2")},
+			},
+		}
+	}
+	return list
+}
+
+func benchmarkNormalize(b *testing.B, n int) {
+	data := benchmarkList(n)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		var tree Tree
+		tree.Populate(data)
+		b.StartTimer()
+
+		tree.Normalize()
+	}
+}
+
+func BenchmarkNormalize1k(b *testing.B)   { benchmarkNormalize(b, 1000) }
+func BenchmarkNormalize10k(b *testing.B)  { benchmarkNormalize(b, 10000) }
+func BenchmarkNormalize100k(b *testing.B) { benchmarkNormalize(b, 100000) }
+
+func BenchmarkPopulate100k(b *testing.B) {
+	data := benchmarkList(100000)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var tree Tree
+		tree.Populate(data)
+	}
+}
@@ -0,0 +1,85 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"strconv"
+)
+
+// Returns the type of an integer field. Without a tracked range this is
+// always int64. If config.narrow is set the narrowest type holding the range
+// is used, unsigned if no negative values were observed. Ranges no integer
+// type holds use the type given by config.big.
+func (f *formatter) integer(t *Tree) string {
+	s := t.values()
+	if s == nil || s.Min == nil {
+		return Int.String()
+	}
+
+	if config.narrow {
+		if name, ok := narrowest(s.Min, s.Max, t.List); ok {
+			return name
+		}
+	} else if !t.big() {
+		return Int.String()
+	}
+
+	switch config.big {
+	case "number":
+		f.imports["encoding/json"] = true
+		return "json.Number"
+	case "bigint":
+		f.imports["math/big"] = true
+		return "*big.Int"
+	}
+	return Float.String()
+}
+
+// Returns the narrowest sized integer type holding the range min to max, no
+// narrower than config.narrowBits. Lists are never uint8, as encoding/json
+// encodes []uint8 as a base64 string.
+func narrowest(min, max *big.Int, list bool) (string, bool) {
+	one := big.NewInt(1)
+
+	for bits := uint(8); bits <= 64; bits *= 2 {
+		if int(bits) < config.narrowBits {
+			continue
+		}
+
+		// Unsigned types hold 0 to 2^bits - 1.
+		if min.Sign() >= 0 {
+			if list && bits == 8 {
+				continue
+			}
+
+			limit := new(big.Int).Lsh(one, bits)
+			if max.Cmp(limit) < 0 {
+				return "uint" + strconv.Itoa(int(bits)), true
+			}
+			continue
+		}
+
+		// Signed types hold -2^(bits-1) to 2^(bits-1) - 1.
+		limit := new(big.Int).Lsh(one, bits-1)
+		if max.Cmp(limit) < 0 && min.Cmp(new(big.Int).Neg(limit)) >= 0 {
+			return "int" + strconv.Itoa(int(bits)), true
+		}
+	}
+
+	return "", false
+}
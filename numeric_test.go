@@ -0,0 +1,125 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestNarrowest(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.narrow = true
+		c.narrowBits = 8
+		c.big = "float64"
+	})()
+
+	testCases := []struct {
+		Min, Max string
+		Type     string
+	}{
+		{"0", "0", "uint8"},
+		{"0", "255", "uint8"},
+		{"0", "256", "uint16"},
+		{"1", "4294967295", "uint32"},
+		{"0", "18446744073709551615", "uint64"},
+		{"0", "18446744073709551616", ""},
+		{"-1", "0", "int8"},
+		{"-128", "127", "int8"},
+		{"-129", "127", "int16"},
+		{"-1", "128", "int16"},
+		{"-2147483648", "2147483647", "int32"},
+		{"-9223372036854775808", "9223372036854775807", "int64"},
+		{"-9223372036854775809", "0", ""},
+	}
+
+	for _, testCase := range testCases {
+		min, _ := new(big.Int).SetString(testCase.Min, 10)
+		max, _ := new(big.Int).SetString(testCase.Max, 10)
+
+		name, ok := narrowest(min, max, false)
+		if name != testCase.Type || ok != (testCase.Type != "") {
+			t.Errorf("Min: %s Max: %s Expected: %q Got: %q", testCase.Min, testCase.Max, testCase.Type, name)
+		}
+	}
+
+	if name, _ := narrowest(big.NewInt(0), big.NewInt(255), true); name != "uint16" {
+		t.Errorf("Expected: %q Got: %q", "uint16", name)
+	}
+
+	config.narrowBits = 32
+	if name, _ := narrowest(big.NewInt(-1), big.NewInt(1), false); name != "int32" {
+		t.Errorf("Expected: %q Got: %q", "int32", name)
+	}
+}
+
+func TestNumericFormat(t *testing.T) {
+	type formatCase struct {
+		Narrow   bool
+		Big      string
+		Source   string
+		Expected string
+	}
+
+	formatCases := []formatCase{
+		// Ranges aren't tracked by default.
+		{false, "float64", `[1, 18446744073709551615]`, "type _ []float64\n"},
+		{true, "float64", `[1, 300]`, "type _ []uint16\n"},
+		// Lists of bytes would be encoded as base64.
+		{true, "float64", `[1, 2]`, "type _ []uint16\n"},
+		{true, "float64", `[-1, 2]`, "type _ []int8\n"},
+		{true, "float64", `{"a": 1}`, "type _ struct {\n\tA uint8 `json:\"a\"`\n}\n"},
+		{true, "float64", `[-1, 300]`, "type _ []int16\n"},
+		{true, "float64", `[1, 18446744073709551615]`, "type _ []uint64\n"},
+		{true, "float64", `[1, 1.5]`, "type _ []float64\n"},
+		{true, "float64", `[-1, 18446744073709551615]`, "type _ []float64\n"},
+		{true, "number", `[-1, 18446744073709551615]`, "import \"encoding/json\"\n\ntype _ []json.Number\n"},
+		{true, "bigint", `[-1, 18446744073709551615]`, "import \"math/big\"\n\ntype _ []*big.Int\n"},
+		{false, "bigint", `[1, 2]`, "type _ []int64\n"},
+		{false, "bigint", `[1, 18446744073709551615]`, "import \"math/big\"\n\ntype _ []*big.Int\n"},
+		// Ranges of fields are merged while squashing.
+		{true, "float64", `[{"id": 1}, {"id": 70000}]`, "type _ []struct {\n\tId uint32 `json:\"id\"`\n}\n"},
+		{false, "number", `[{"id": 1}, {"id": 18446744073709551616}]`, "import \"encoding/json\"\n\ntype _ []struct {\n\tId json.Number `json:\"id\"`\n}\n"},
+	}
+
+	for _, testCase := range formatCases {
+		restore := withConfig(func(c *Config) {
+			c.narrow = testCase.Narrow
+			c.narrowBits = 8
+			c.big = testCase.Big
+		})
+
+		tree, err := Parse(testCase.Source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		TreeTestCase{testCase.Expected, tree}.TestFormat(t)
+
+		restore()
+	}
+}
+
+func TestNumericValidate(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.narrow = false
+		c.narrowBits = 8
+		c.big = "number"
+	})()
+
+	ValidateTestCase{`[1, 18446744073709551616]`, `[2, 36893488147419103232]`, nil}.TestValidate(t)
+	ValidateTestCase{`[1, 18446744073709551616]`, `[2.5]`, []ValidationError{{".[0]", TypeMismatch, "expected int64, got number"}}}.TestValidate(t)
+}
@@ -0,0 +1,140 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Standard input, replaced by what was read from it if it had to be read
+// before it was inferred from.
+var stdin io.Reader = os.Stdin
+
+// Populates the tree from the named files, or standard input if there are
+// none. Multiple files are inferred concurrently by up to jobs workers and
+// merged in the order they were given, so the result doesn't depend on
+// scheduling.
+func InferFiles(t *Tree, filenames []string, jobs int) error {
+	switch len(filenames) {
+	case 0:
+		return t.inferReader(stdin, config.normalize)
+	case 1:
+		return t.inferFile(filenames[0], config.normalize)
+	}
+
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	trees := make([]*Tree, len(filenames))
+	errs := make([]error, len(filenames))
+
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < jobs; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range indices {
+				// Trees must be normalized to be merged.
+				trees[idx] = &Tree{}
+				errs[idx] = trees[idx].inferFile(filenames[idx], true)
+			}
+		}()
+	}
+
+	for idx := range filenames {
+		indices <- idx
+	}
+	close(indices)
+	wg.Wait()
+
+	// Report the error of the first file which failed.
+	for idx, err := range errs {
+		if err != nil {
+			return fmt.Errorf("%s: %s", filenames[idx], err)
+		}
+	}
+
+	*t = Merge(trees...)
+	return nil
+}
+
+func (t *Tree) inferFile(filename string, normalize bool) error {
+	inputFile, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer inputFile.Close()
+
+	return t.inferReader(inputFile, normalize)
+}
+
+func (t *Tree) inferReader(r io.Reader, normalize bool) error {
+	if config.stream {
+		return t.Infer(json.NewDecoder(r))
+	}
+
+	jsonDecoder := json.NewDecoder(r)
+	jsonDecoder.UseNumber()
+	var data interface{}
+	if err := jsonDecoder.Decode(&data); err != nil {
+		return err
+	}
+
+	t.Populate(data)
+	if normalize {
+		t.Normalize()
+	}
+
+	return nil
+}
+
+// Merges normalized trees as if each were an element of the same list. Trees
+// which are lists conflict with those which aren't, the result is the empty
+// interface. Merging is associative, merging the results of merging
+// consecutive runs of trees produces the same tree as merging all of them at
+// once. Trees may be modified while merging. Unlike the elements of a list,
+// trees are never treated as the positions of a tuple.
+func Merge(trees ...*Tree) (merged Tree) {
+	lists := 0
+	for _, t := range trees {
+		if t.List {
+			lists++
+		}
+	}
+
+	e := newElements(newSigner(), false)
+	for _, t := range trees {
+		e.add(t)
+	}
+
+	if lists > 0 && lists < len(trees) {
+		merged.Type = Interface
+	} else {
+		merged.List = lists > 0
+		e.finish(&merged)
+	}
+	merged.Stats = e.stats
+	merged.ResolveTuples()
+
+	return
+}
@@ -0,0 +1,144 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var mergeSources = [][]string{
+	{`1`, `2`},
+	{`1`, `2.5`},
+	{`1`, `"foo"`, `true`},
+	{`null`, `1`},
+	{`[1]`, `[2.5]`},
+	{`[1]`, `2`},
+	{`{"a": 1}`, `{"b": "foo"}`},
+	{`{"a": 1}`, `{"a": "foo"}`, `{"a": 1}`},
+	{`{"a": [1]}`, `{"a": "foo"}`, `{"a": [2]}`},
+	{`{"a": {"b": 1}}`, `{"a": {"b": 1}}`, `{"a": {"b": 1.5}}`, `{"c": true}`},
+	{`[{"a": 1}]`, `[{"b": 2}]`, `{"c": 3}`},
+	{`{"a": 1}`, `2`, `{"a": 1}`},
+}
+
+func parseAll(t *testing.T, sources []string) (trees []*Tree) {
+	for _, source := range sources {
+		tree, err := Parse(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		trees = append(trees, &tree)
+	}
+	return
+}
+
+// Merging files must produce the same type as elements of a list would,
+// unless only some of them are lists.
+func TestMerge(t *testing.T) {
+	for _, sources := range mergeSources {
+		list := "[" + strings.Join(sources, ",") + "]"
+		expected, err := Parse(list)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		lists := 0
+		for _, source := range sources {
+			if strings.HasPrefix(source, "[") {
+				lists++
+			}
+		}
+		expected.List = lists == len(sources)
+		if lists > 0 && lists < len(sources) {
+			expected = Tree{Type: Interface}
+		}
+
+		merged := Merge(parseAll(t, sources)...)
+		if !reflect.DeepEqual(merged, expected) {
+			t.Errorf("Sources: %s Expected: %+v Got: %+v", sources, expected, merged)
+		}
+	}
+}
+
+func TestMergeAssociative(t *testing.T) {
+	for _, sources := range mergeSources {
+		flat := Merge(parseAll(t, sources)...)
+
+		for split := 1; split < len(sources); split++ {
+			left := Merge(parseAll(t, sources[:split])...)
+			right := Merge(parseAll(t, sources[split:])...)
+
+			merged := Merge(&left, &right)
+			if !reflect.DeepEqual(merged, flat) {
+				t.Errorf("Sources: %s Split: %d Expected: %+v Got: %+v", sources, split, flat, merged)
+			}
+		}
+	}
+}
+
+func TestInferFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jsongen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var sources, filenames []string
+	for idx := 0; idx < 64; idx++ {
+		source := `{"id": ` + strconv.Itoa(idx) + `, "field` + strconv.Itoa(idx%8) + `": "foo"}`
+		if idx%16 == 0 {
+			source = `{"id": "` + strconv.Itoa(idx) + `"}`
+		}
+
+		filename := filepath.Join(dir, strconv.Itoa(idx)+".json")
+		if err := ioutil.WriteFile(filename, []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		sources = append(sources, source)
+		filenames = append(filenames, filename)
+	}
+
+	expected := Merge(parseAll(t, sources)...)
+
+	for _, jobs := range []int{0, 1, 4, 64} {
+		var tree Tree
+		if err := InferFiles(&tree, filenames, jobs); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(tree, expected) {
+			t.Errorf("Jobs: %d Expected: %+v Got: %+v", jobs, expected, tree)
+		}
+	}
+
+	// The first file to fail is reported regardless of scheduling.
+	missing := append([]string{}, filenames...)
+	missing[3] = filepath.Join(dir, "missing.json")
+	missing[7] = filepath.Join(dir, "also-missing.json")
+
+	var tree Tree
+	if err := InferFiles(&tree, missing, 8); err == nil || !strings.HasPrefix(err.Error(), missing[3]) {
+		t.Errorf("Expected error for %s Got: %v", missing[3], err)
+	}
+}
@@ -0,0 +1,363 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Matches keys which are valid proto field names.
+var protoIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Proto scalar types, which are optional rather than messages when fields
+// may be missing.
+var protoScalars = map[string]bool{"bool": true, "int64": true, "uint64": true, "double": true, "string": true, "bytes": true}
+
+func init() {
+	RegisterEmitter("proto", protobuf{})
+}
+
+// Emits proto3 message definitions: a message for each struct, numbered
+// fields named after their keys in snake case, repeated fields for lists and
+// well known types for timestamps and values of unknown type. Objects whose
+// keys are data rather than field names are maps.
+type protobuf struct{}
+
+func (protobuf) Emit(g *Graph) ([]byte, []string, error) {
+	p := &protoWriter{imports: make(map[string]bool), declared: make(map[*Decl]bool), path: "."}
+
+	// Only messages can be declared, so other types are wrapped in one.
+	if r := g.Root; r.Decl != nil && r.Decl.Name == g.Name && r.Type == Struct && !r.List {
+		p.message(r.Decl)
+	} else {
+		p.note("top level type isn't an object, wrapped in message %s as its field value", g.Name)
+		p.message(&Decl{Name: g.Name, Type: Struct, Fields: []*Field{{Name: "Value", Key: "value", Type: r}}})
+	}
+
+	source := "syntax = \"proto3\";\n"
+
+	var imports []string
+	for path := range p.imports {
+		imports = append(imports, "import "+strconv.Quote(path)+";\n")
+	}
+	sort.Strings(imports)
+	if len(imports) > 0 {
+		source += "\n" + strings.Join(imports, "")
+	}
+
+	for _, message := range p.messages {
+		source += "\n" + message
+	}
+
+	return []byte(source), p.diagnostics, nil
+}
+
+// Declares the messages a type graph requires as they're referred to.
+type protoWriter struct {
+	imports  map[string]bool
+	messages []string
+	declared map[*Decl]bool
+
+	// Path of the field being declared.
+	path        string
+	diagnostics []string
+}
+
+// Records a note about the field being declared.
+func (p *protoWriter) note(format string, args ...interface{}) {
+	p.diagnostics = append(p.diagnostics, p.path+": "+fmt.Sprintf(format, args...))
+}
+
+// Returns the name of a message in google/protobuf, importing the file
+// declaring it.
+func (p *protoWriter) wellKnown(name, file string) string {
+	p.imports["google/protobuf/"+file+".proto"] = true
+	return "google.protobuf." + name
+}
+
+// Declares a message for a struct or union, and the messages of its fields.
+// Messages are declared before those of their fields.
+func (p *protoWriter) message(d *Decl) {
+	if p.declared[d] {
+		return
+	}
+	p.declared[d] = true
+
+	idx := len(p.messages)
+	p.messages = append(p.messages, "")
+
+	decl := "message " + d.Name + " {\n"
+	fields := d.Fields
+	if d.Type == Union {
+		var values []string
+		for _, variant := range d.Variants {
+			values = append(values, variant.Value)
+		}
+		decl = "// Variants by " + d.Discriminator + ": " + strings.Join(values, ", ") + ".\n" + decl
+		fields = variantFields(d)
+	}
+
+	names := newNamer("_")
+	number := 0
+	for _, field := range fields {
+		// Field numbers are assigned in order, skipping those reserved
+		// for the implementation.
+		if number++; number == 19000 {
+			number = 20000
+		}
+
+		path := p.path
+		p.path = joinPath(path, field.Key)
+		typ, repeated := p.typ(field.Type)
+		p.path = path
+
+		unique := names.declare(protoName(field.Key))
+
+		line := typ + " " + unique + " = " + strconv.Itoa(number)
+		switch {
+		case repeated:
+			line = "repeated " + line
+		case protoScalars[typ] && (field.Optional || field.Type.Nullable):
+			line = "optional " + line
+		}
+		if jsonName(unique) != field.Key {
+			line += " [json_name = " + strconv.Quote(field.Key) + "]"
+		}
+
+		for _, doc := range field.Doc {
+			decl += "  // " + doc + "\n"
+		}
+		decl += "  " + line + ";\n"
+	}
+
+	p.messages[idx] = decl + "}\n"
+}
+
+// Returns the proto type of a reference and whether it's repeated,
+// declaring any messages it requires.
+func (p *protoWriter) typ(r *Ref) (typ string, repeated bool) {
+	switch r.Type {
+	case Bool:
+		typ = "bool"
+	case Int:
+		// Integers beyond int64 are uint64 if none are negative and they
+		// fit, doubles otherwise.
+		typ = "int64"
+		if r.big() {
+			if r.Min.Sign() >= 0 && r.Max.BitLen() <= 64 {
+				typ = "uint64"
+			} else {
+				p.note("integers out of the range of int64 and uint64, declared as double")
+				typ = "double"
+			}
+		}
+	case Float:
+		typ = "double"
+	case String:
+		typ = "string"
+		if r.Format == "time" {
+			typ = p.wellKnown("Timestamp", "timestamp")
+		} else if r.Bytes {
+			typ = "bytes"
+		}
+	case Struct, Union:
+		value := mapValue(r.Decl)
+		switch {
+		case value != nil && r.List:
+			// Maps can't be repeated.
+			p.note("list of objects with keys which aren't field names, declared as google.protobuf.Struct")
+			typ = p.wellKnown("Struct", "struct")
+		case value != nil:
+			// Nor can the values of maps be maps.
+			var v string
+			if value.Decl != nil && mapValue(value.Decl) != nil {
+				v = p.wellKnown("Struct", "struct")
+			} else {
+				v, _ = p.typ(value)
+			}
+			typ = "map<string, " + v + ">"
+		default:
+			p.message(r.Decl)
+			typ = r.Decl.Name
+		}
+	case Tuple:
+		typ = p.wellKnown("ListValue", "struct")
+	default:
+		typ = p.wellKnown("Value", "struct")
+	}
+	return typ, r.List
+}
+
+// Returns the reference to the values of a struct whose keys are data rather
+// than field names, nil if it isn't one. A struct is a map if any of its keys
+// isn't a valid field name and its values all have the same type, which
+// isn't a list.
+func mapValue(d *Decl) *Ref {
+	if d.Type != Struct || d.Value != "" || len(d.Fields) == 0 {
+		return nil
+	}
+
+	named := true
+	value := d.Fields[0].Type
+	for _, field := range d.Fields {
+		named = named && protoIdentifier.MatchString(field.Key)
+		if field.Type.List || field.Type.Decl == d || !sameType(value, field.Type) {
+			return nil
+		}
+	}
+
+	if named {
+		return nil
+	}
+	return value
+}
+
+// Returns true if two references have the same type. Declarations named
+// after different keys are the same type if their fields are, recursive
+// references only if they refer to the same declaration.
+func sameType(a, b *Ref) bool {
+	if a.Type != b.Type || a.List != b.List || a.Format != b.Format || a.Recursive != b.Recursive {
+		return false
+	}
+
+	switch {
+	case a.Type == Tuple:
+		if len(a.Positions) != len(b.Positions) {
+			return false
+		}
+		for idx, position := range a.Positions {
+			if !sameType(position, b.Positions[idx]) {
+				return false
+			}
+		}
+		return true
+	case a.Decl == b.Decl:
+		return true
+	case a.Decl == nil || b.Decl == nil || a.Recursive:
+		return false
+	}
+	return sameDecl(a.Decl, b.Decl)
+}
+
+// Returns true if two declarations have fields with the same keys and
+// types, and variants for the same values.
+func sameDecl(a, b *Decl) bool {
+	if a.Type != b.Type || a.Discriminator != b.Discriminator || a.Value != b.Value ||
+		len(a.Fields) != len(b.Fields) || len(a.Variants) != len(b.Variants) {
+		return false
+	}
+
+	for idx, field := range a.Fields {
+		if field.Key != b.Fields[idx].Key || !sameType(field.Type, b.Fields[idx].Type) {
+			return false
+		}
+	}
+	for idx, variant := range a.Variants {
+		if !sameDecl(variant, b.Variants[idx]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the fields of every variant of a union. Fields missing from some
+// variants are optional, those whose type differs between variants are
+// values of any type and those documented differently are undocumented.
+func variantFields(d *Decl) (fields []*Field) {
+	byKey := make(map[string]*Field)
+	variants := make(map[string]int)
+	for _, variant := range d.Variants {
+		for _, field := range variant.Fields {
+			variants[field.Key]++
+
+			merged, ok := byKey[field.Key]
+			if !ok {
+				c := *field
+				byKey[field.Key] = &c
+				fields = append(fields, &c)
+				continue
+			}
+
+			if !sameType(merged.Type, field.Type) {
+				merged.Type = &Ref{Type: Interface, List: merged.Type.List && field.Type.List}
+			}
+			merged.Optional = merged.Optional || field.Optional
+			if strings.Join(merged.Doc, "\n") != strings.Join(field.Doc, "\n") {
+				merged.Doc = nil
+			}
+		}
+	}
+
+	for _, field := range fields {
+		field.Optional = field.Optional || variants[field.Key] < len(d.Variants)
+	}
+	return fields
+}
+
+// Returns a proto field name for a key, in snake case.
+func protoName(key string) string {
+	var name []byte
+	var prev byte
+	for idx := 0; idx < len(key); idx++ {
+		c := key[idx]
+		switch {
+		case c >= 'A' && c <= 'Z':
+			// Words start at an upper case letter following a lower case
+			// letter or digit, or preceding one in an acronym.
+			next := idx+1 < len(key) && key[idx+1] >= 'a' && key[idx+1] <= 'z'
+			if prev >= 'a' && prev <= 'z' || prev >= '0' && prev <= '9' || prev >= 'A' && prev <= 'Z' && next {
+				name = append(name, '_')
+			}
+			name = append(name, c+'a'-'A')
+		case c >= 'a' && c <= 'z' || c >= '0' && c <= '9':
+			name = append(name, c)
+		case len(name) > 0 && name[len(name)-1] != '_':
+			name = append(name, '_')
+		}
+		prev = c
+	}
+
+	s := strings.TrimRight(string(name), "_")
+	if s == "" || s[0] >= '0' && s[0] <= '9' {
+		s = "field_" + s
+	}
+	return strings.TrimRight(s, "_")
+}
+
+// Returns the JSON name protoc derives from a field name, in lower camel
+// case.
+func jsonName(name string) string {
+	var json []byte
+	upper := false
+	for idx := 0; idx < len(name); idx++ {
+		c := name[idx]
+		switch {
+		case c == '_':
+			upper = true
+			continue
+		case upper && c >= 'a' && c <= 'z':
+			c -= 'a' - 'A'
+		}
+		upper = false
+		json = append(json, c)
+	}
+	return string(json)
+}
@@ -0,0 +1,160 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProto(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.lang = "proto"
+		c.parseDetectors("time", "")
+	})()
+
+	testCases := []struct {
+		Source   string
+		Expected string
+	}{
+		{`{"a": true, "b": 1, "c": 1.5, "d": "foo", "e": null}`,
+			"syntax = \"proto3\";\n\n" +
+				"import \"google/protobuf/struct.proto\";\n\n" +
+				"message Root {\n  bool a = 1;\n  int64 b = 2;\n  double c = 3;\n  string d = 4;\n  google.protobuf.Value e = 5;\n}\n"},
+		// Fields are named in snake case, with the key as their JSON name if
+		// protoc wouldn't derive it.
+		{`{"userId": 1, "created_at": "2021-04-05T10:00:00Z", "_id": "x"}`,
+			"syntax = \"proto3\";\n\n" +
+				"import \"google/protobuf/timestamp.proto\";\n\n" +
+				"message Root {\n" +
+				"  // Detected: RFC 3339 timestamp.\n" +
+				"  google.protobuf.Timestamp created_at = 1 [json_name = \"created_at\"];\n" +
+				"  string id = 2 [json_name = \"_id\"];\n" +
+				"  int64 user_id = 3;\n" +
+				"}\n"},
+		// Lists are repeated and scalars which may be missing are optional.
+		{`{"items": [{"id": 1}, {"id": 2, "name": "foo"}], "tags": ["a"]}`,
+			"syntax = \"proto3\";\n\n" +
+				"message Root {\n  repeated Items items = 1;\n  repeated string tags = 2;\n}\n\n" +
+				"message Items {\n  int64 id = 1;\n  optional string name = 2;\n}\n"},
+		// Objects with keys which aren't field names are maps.
+		{`{"rates": {"EUR-USD": 1.1, "GBP-USD": 1.3}, "users": {"1": {"id": 1}, "2": {"id": 2}}}`,
+			"syntax = \"proto3\";\n\n" +
+				"message Root {\n  map<string, double> rates = 1;\n  map<string, Object> users = 2;\n}\n\n" +
+				"message Object {\n  int64 id = 1;\n}\n"},
+		{`[1, 2]`,
+			"syntax = \"proto3\";\n\nmessage Root {\n  repeated int64 value = 1;\n}\n"},
+	}
+
+	for _, testCase := range testCases {
+		tree, err := Parse(testCase.Source)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		formatted, _, err := protobuf{}.Emit(tree.Graph())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(formatted) != testCase.Expected {
+			t.Errorf("Source: %s Expected: %q Got: %q", testCase.Source, testCase.Expected, formatted)
+		}
+	}
+}
+
+// Integers beyond int64 are uint64 if they fit, doubles otherwise.
+func TestProtoBig(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.lang = "proto"
+	})()
+
+	tree, err := Parse(`{"a": 18446744073709551615, "b": [-1, 18446744073709551615], "c": 1}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	formatted, diagnostics, err := protobuf{}.Emit(tree.Graph())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "syntax = \"proto3\";\n\nmessage Root {\n  uint64 a = 1;\n  repeated double b = 2;\n  int64 c = 3;\n}\n"
+	if string(formatted) != expected {
+		t.Errorf("Expected: %q Got: %q", expected, formatted)
+	}
+
+	expectedDiagnostics := []string{".b: integers out of the range of int64 and uint64, declared as double"}
+	if !reflect.DeepEqual(diagnostics, expectedDiagnostics) {
+		t.Errorf("Expected: %q Got: %q", expectedDiagnostics, diagnostics)
+	}
+}
+
+// Unions are a message with the fields of every variant.
+func TestProtoUnion(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.lang = "proto"
+		c.unions = true
+		c.unionMax = 16
+	})()
+
+	tree, err := Parse(`[{"type": "circle", "r": 1}, {"type": "square", "w": 2, "r": "x"}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	formatted, diagnostics, err := protobuf{}.Emit(tree.Graph())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "syntax = \"proto3\";\n\n" +
+		"import \"google/protobuf/struct.proto\";\n\n" +
+		"message Root {\n  repeated RootElement value = 1;\n}\n\n" +
+		"// Variants by type: circle, square.\n" +
+		"message RootElement {\n  google.protobuf.Value r = 1;\n  string type = 2;\n  optional int64 w = 3;\n}\n"
+	if string(formatted) != expected {
+		t.Errorf("Expected: %q Got: %q", expected, formatted)
+	}
+
+	expectedDiagnostics := []string{".: top level type isn't an object, wrapped in message Root as its field value"}
+	if !reflect.DeepEqual(diagnostics, expectedDiagnostics) {
+		t.Errorf("Expected: %q Got: %q", expectedDiagnostics, diagnostics)
+	}
+}
+
+func TestProtoName(t *testing.T) {
+	testCases := []struct {
+		Key      string
+		Expected string
+		JSON     string
+	}{
+		{"name", "name", "name"},
+		{"userId", "user_id", "userId"},
+		{"HTTPStatus", "http_status", "httpStatus"},
+		{"content-type", "content_type", "contentType"},
+		{"_id", "id", "id"},
+		{"42", "field_42", "field42"},
+		{"$", "field", "field"},
+	}
+
+	for _, testCase := range testCases {
+		name := protoName(testCase.Key)
+		if name != testCase.Expected || jsonName(name) != testCase.JSON {
+			t.Errorf("Key: %q Expected: %q %q Got: %q %q", testCase.Key, testCase.Expected, testCase.JSON, name, jsonName(name))
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+// Returns the type of a string field whose values all hold integers, numbers
+// or bools, if quoted values are enabled for the field's path. Such fields
+// are decoded with the ,string tag option, which only applies to fields
+// which aren't lists. Integers beyond int64 are uint64 if it holds them,
+// otherwise they're left as strings rather than lose precision as floats.
+func (f *formatter) quoted(t *Tree) (name string, ok bool) {
+	enabled, configured := config.quotedPaths[f.path]
+	if !configured {
+		enabled = config.quoted
+	}
+
+	s := t.values()
+	if !enabled || t.List || t.Type != String || s == nil || s.Strings == 0 {
+		return "", false
+	}
+
+	switch s.Strings {
+	case s.IntStrings:
+		switch min, max := s.MinIntString, s.MaxIntString; {
+		case min == nil || min.IsInt64() && max.IsInt64():
+			return Int.String(), true
+		case min.Sign() >= 0 && max.BitLen() <= 64:
+			return "uint64", true
+		}
+		return "", false
+	case s.FloatStrings:
+		return Float.String(), true
+	case s.BoolStrings:
+		return Bool.String(), true
+	}
+	return "", false
+}
@@ -0,0 +1,118 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNumberLiteral(t *testing.T) {
+	testCases := []struct {
+		Source string
+		Valid  bool
+	}{
+		{"0", true}, {"-1", true}, {"12345", true}, {"19.99", true}, {"1e10", true}, {"-1.5E-3", true},
+		{"", false}, {"01", false}, {"+1", false}, {"1.", false}, {".5", false}, {"0x10", false}, {" 1", false}, {"NaN", false},
+	}
+
+	for _, testCase := range testCases {
+		if numberLiteral.MatchString(testCase.Source) != testCase.Valid {
+			t.Errorf("Source: %q Expected: %t", testCase.Source, testCase.Valid)
+		}
+
+		// Anything matched must be accepted by encoding/json.
+		var f struct {
+			F float64 `json:",string"`
+		}
+		b, _ := json.Marshal(map[string]string{"F": testCase.Source})
+		if err := json.Unmarshal(b, &f); testCase.Valid && err != nil {
+			t.Errorf("Source: %q Error: %s", testCase.Source, err)
+		}
+	}
+}
+
+func TestQuotedFormat(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.quoted = true
+		c.quotedPaths = map[string]bool{".skip": false}
+	})()
+
+	tree, err := Parse(`[
+		{"id": "12345", "amount": "19.99", "flag": "true", "name": "foo", "skip": "1", "list": ["1"], "mixed": "1"},
+		{"id": "67890", "amount": "20", "flag": "false", "name": "1", "skip": "2", "list": ["2"], "mixed": "false"}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "type _ []struct {\n" +
+		"\tAmount float64  `json:\"amount,string\"`\n" +
+		"\tFlag   bool     `json:\"flag,string\"`\n" +
+		"\tId     int64    `json:\"id,string\"`\n" +
+		"\tList   []string `json:\"list\"`\n" +
+		"\tMixed  string   `json:\"mixed\"`\n" +
+		"\tName   string   `json:\"name\"`\n" +
+		"\tSkip   string   `json:\"skip\"`\n" +
+		"}\n"
+
+	TreeTestCase{expected, tree}.TestFormat(t)
+
+	// Integers beyond int64 are uint64 if it holds them, otherwise strings.
+	tree, err = Parse(`{"id": "18446744073709551615", "big": "18446744073709551616", "neg": "-9223372036854775809"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = "type _ struct {\n" +
+		"\tBig string `json:\"big\"`\n" +
+		"\tId  uint64 `json:\"id,string\"`\n" +
+		"\tNeg string `json:\"neg\"`\n" +
+		"}\n"
+
+	TreeTestCase{expected, tree}.TestFormat(t)
+}
+
+func TestQuotedPaths(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.quoted = false
+		c.quotedPaths = map[string]bool{".a.id": true}
+	})()
+
+	tree, err := Parse(`{"id": "1", "a": {"id": "2"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "type _ struct {\n" +
+		"\tA struct {\n" +
+		"\t\tId int64 `json:\"id,string\"`\n" +
+		"\t} `json:\"a\"`\n" +
+		"\tId string `json:\"id\"`\n" +
+		"}\n"
+
+	TreeTestCase{expected, tree}.TestFormat(t)
+
+	// The root isn't a field so has no tag.
+	config.quotedPaths = nil
+	config.quoted = true
+	tree, err = Parse(`"1"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	TreeTestCase{"type _ string\n", tree}.TestFormat(t)
+}
@@ -0,0 +1,94 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import "testing"
+
+func TestRawFormat(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.raw = true
+	})()
+
+	testCases := []struct {
+		Source   string
+		Expected string
+	}{
+		{
+			`{"a": null, "b": [1, "foo"], "c": [{"d": 1}, {"d": "foo"}], "e": 1}`,
+			"import \"encoding/json\"\n" +
+				"\n" +
+				"type _ struct {\n" +
+				"\tA json.RawMessage   `json:\"a\"`\n" +
+				"\tB []json.RawMessage `json:\"b\"`\n" +
+				"\tC []struct {\n" +
+				"\t\tD json.RawMessage `json:\"d\"`\n" +
+				"\t} `json:\"c\"`\n" +
+				"\tE int64 `json:\"e\"`\n" +
+				"}\n",
+		},
+		{`null`, "import \"encoding/json\"\n\ntype _ json.RawMessage\n"},
+		// Without unknown values nothing is imported.
+		{`{"a": 1}`, "type _ struct {\n\tA int64 `json:\"a\"`\n}\n"},
+	}
+
+	for _, testCase := range testCases {
+		tree, err := Parse(testCase.Source)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		formatted, err := tree.Format()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(formatted) != testCase.Expected {
+			t.Errorf("Source: %s Expected: %q Got: %q", testCase.Source, testCase.Expected, formatted)
+		}
+	}
+}
+
+// The import is shared with other options which use encoding/json.
+func TestRawImport(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.raw = true
+		c.big = "number"
+		c.narrow = true
+		c.narrowBits = 8
+	})()
+
+	tree, err := Parse(`{"a": null, "b": 100000000000000000000}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	formatted, err := tree.Format()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "import \"encoding/json\"\n" +
+		"\n" +
+		"type _ struct {\n" +
+		"\tA json.RawMessage `json:\"a\"`\n" +
+		"\tB json.Number     `json:\"b\"`\n" +
+		"}\n"
+
+	if string(formatted) != expected {
+		t.Errorf("Expected: %q Got: %q", expected, formatted)
+	}
+}
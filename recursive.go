@@ -0,0 +1,221 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+// Replaces structs which share the shape of a struct they're nested in with
+// references to it, so self-similar trees of any depth have the same type. A
+// struct shares the shape of an ancestor if it has the field leading from the
+// ancestor to it and each of its fields is a field of the ancestor with a
+// compatible type. Fields may be missing and null or empty lists are
+// compatible with anything, so the deepest levels of a sample match.
+func (t *Tree) DetectRecursion() {
+	r := &recursion{targets: make(map[string]*Tree)}
+	r.detect(t, ".", nil)
+}
+
+type recursion struct {
+	// Structs by path, so references can be compared with what they refer
+	// to.
+	targets map[string]*Tree
+}
+
+// A struct enclosing the one being detected and its field leading there.
+type ancestor struct {
+	path string
+	tree *Tree
+	edge Ident
+}
+
+func (r *recursion) detect(t *Tree, path string, ancestors []ancestor) {
+	if t.Type != Struct {
+		return
+	}
+
+	// The outermost ancestor matched is referred to, and observes the values
+	// of the struct replaced.
+	for _, a := range ancestors {
+		if t.field(a.edge) != nil && r.matches(t, a.tree) {
+			replaced := *t
+			t.Type = Recursive
+			t.Ref = a.path
+			t.Children = nil
+			r.mergeStruct(a.tree, &replaced)
+			return
+		}
+	}
+
+	r.targets[path] = t
+	for _, child := range t.Children {
+		next := append(ancestors[:len(ancestors):len(ancestors)], ancestor{path, t, child.Name})
+		r.detect(child, joinPath(path, string(child.Name)), next)
+	}
+}
+
+// Merges the stats of a struct replaced with a reference into the struct it
+// refers to, field by field.
+func (r *recursion) mergeStruct(target, t *Tree) {
+	if s := target.values(); s != nil {
+		mergeStats(s, t.values())
+	}
+	for _, child := range t.Children {
+		if field := target.field(child.Name); field != nil {
+			r.mergeField(field, child)
+		}
+	}
+}
+
+// Merges the stats of a field into the field of the same name it matched.
+// Nested structs which are references themselves are merged into the struct
+// they refer to.
+func (r *recursion) mergeField(field, t *Tree) {
+	field.Stats = mergeStats(field.Stats, t.Stats)
+	if t.Type != Struct {
+		return
+	}
+
+	if field.Type == Recursive {
+		if target, ok := r.targets[field.Ref]; ok {
+			r.mergeStruct(target, t)
+		}
+		return
+	}
+	for _, child := range t.Children {
+		if f := field.field(child.Name); f != nil {
+			r.mergeField(f, child)
+		}
+	}
+}
+
+// Returns true if every field of a struct is a field of another with a
+// compatible type.
+func (r *recursion) matches(t, other *Tree) bool {
+	for _, child := range t.Children {
+		field := other.field(child.Name)
+		if field == nil || !r.compatible(child, field) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns true if two fields of the same name could hold the same values.
+func (r *recursion) compatible(a, b *Tree) bool {
+	if a.Type == Recursive && b.Type == Recursive {
+		return a.List == b.List && a.Ref == b.Ref
+	}
+
+	a, b = r.resolve(a), r.resolve(b)
+	switch {
+	case a.Type == Interface || b.Type == Interface:
+		return true
+	case a.List != b.List || a.Type != b.Type:
+		return false
+	case a.Type == Struct:
+		return r.matches(a, b)
+	}
+	return Compare(a, b)
+}
+
+// Returns the struct a recursive type refers to as a field of the same name.
+func (r *recursion) resolve(t *Tree) *Tree {
+	if t.Type != Recursive {
+		return t
+	}
+
+	target := *r.targets[t.Ref]
+	target.Name = t.Name
+	target.List = t.List
+	return &target
+}
+
+// Returns the field of a struct with the given name, nil if there is none.
+func (t *Tree) field(name Ident) *Tree {
+	for _, child := range t.Children {
+		if child.Name == name {
+			return child
+		}
+	}
+	return nil
+}
+
+// Returns the structs of a tree by path.
+func (t *Tree) targets() map[string]*Tree {
+	targets := make(map[string]*Tree)
+
+	var walk func(t *Tree, path string)
+	walk = func(t *Tree, path string) {
+		if t.Type == Struct {
+			targets[path] = t
+		}
+		for _, child := range t.Children {
+			walk(child, joinPath(path, string(child.Name)))
+		}
+	}
+	walk(t, ".")
+
+	return targets
+}
+
+// Records the paths of the structs recursive types refer to, which are
+// declared as named types.
+func (f *formatter) reference(t *Tree) {
+	if t.Type == Recursive {
+		f.refs[t.Ref] = ""
+	}
+	for _, child := range t.Children {
+		f.reference(child)
+	}
+}
+
+// Declares a struct recursive types refer to, named after the field. A named
+// top level type is the struct itself, its elements if it's a list.
+func (f *formatter) referenced(t *Tree) string {
+	name := t.Name.String()
+	if f.path == "." && name != "_" {
+		if !t.List {
+			f.refs[f.path] = name
+			return Struct.String()
+		}
+		name += "Element"
+	}
+	if name == "_" {
+		name = "Node"
+	}
+	name = f.names.declare(name)
+	f.refs[f.path] = name
+
+	// The struct is declared at the top level, outside of the field being
+	// formatted.
+	f.decls = append(f.decls, f.structDecl(name, t))
+
+	return name
+}
+
+// Returns the type a recursive type refers to, a pointer unless it's a list.
+func (f *formatter) recursive(t *Tree) string {
+	name := f.refs[t.Ref]
+	if name == "" {
+		name = f.unknown()
+		f.note("refers to undeclared %s, declared as %s", t.Ref, name)
+		return name
+	}
+
+	if t.List {
+		return name
+	}
+	return "*" + name
+}
@@ -0,0 +1,237 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDetectRecursion(t *testing.T) {
+	testCases := []struct {
+		Name     Ident
+		Source   string
+		Expected string
+	}{
+		{
+			"",
+			`{"category": {"name": "a", "children": [{"name": "b", "children": [{"name": "c", "children": []}, {"name": "d"}]}]}}`,
+			"type _ struct {\n" +
+				"\tCategory Category `json:\"category\"`\n" +
+				"}\n" +
+				"\n" +
+				"type Category struct {\n" +
+				"\tChildren []Category `json:\"children\"`\n" +
+				"\tName     string     `json:\"name\"`\n" +
+				"}\n",
+		},
+		{
+			"",
+			`{"text": "a", "parent": {"text": "b", "parent": {"text": "c", "parent": null}}}`,
+			"type _ Node\n" +
+				"\n" +
+				"type Node struct {\n" +
+				"\tParent *Node  `json:\"parent\"`\n" +
+				"\tText   string `json:\"text\"`\n" +
+				"}\n",
+		},
+		{
+			"",
+			`[{"name": "a", "children": [{"name": "b", "children": []}]}]`,
+			"type _ []Node\n" +
+				"\n" +
+				"type Node struct {\n" +
+				"\tChildren []Node `json:\"children\"`\n" +
+				"\tName     string `json:\"name\"`\n" +
+				"}\n",
+		},
+		// A named top level type is referred to by its name, or by the name
+		// of its elements if it's a list.
+		{
+			"Category",
+			`{"name": "a", "children": [{"name": "b", "children": []}]}`,
+			"type Category struct {\n" +
+				"\tChildren []Category `json:\"children\"`\n" +
+				"\tName     string     `json:\"name\"`\n" +
+				"}\n",
+		},
+		{
+			"Comment",
+			`{"text": "a", "parent": {"text": "b", "parent": null}}`,
+			"type Comment struct {\n" +
+				"\tParent *Comment `json:\"parent\"`\n" +
+				"\tText   string   `json:\"text\"`\n" +
+				"}\n",
+		},
+		{
+			"Categories",
+			`[{"name": "a", "children": [{"name": "b", "children": []}]}]`,
+			"type Categories []CategoriesElement\n" +
+				"\n" +
+				"type CategoriesElement struct {\n" +
+				"\tChildren []CategoriesElement `json:\"children\"`\n" +
+				"\tName     string              `json:\"name\"`\n" +
+				"}\n",
+		},
+		// Nested structs without the field leading to them, or with fields
+		// of other types, aren't recursive.
+		{
+			"",
+			`{"name": "a", "address": {"name": "b"}}`,
+			"type _ struct {\n" +
+				"\tAddress struct {\n" +
+				"\t\tName string `json:\"name\"`\n" +
+				"\t} `json:\"address\"`\n" +
+				"\tName string `json:\"name\"`\n" +
+				"}\n",
+		},
+		{
+			"",
+			`{"id": 1, "child": {"id": "b", "child": null}}`,
+			"type _ struct {\n" +
+				"\tChild struct {\n" +
+				"\t\tChild interface{} `json:\"child\"`\n" +
+				"\t\tId    string      `json:\"id\"`\n" +
+				"\t} `json:\"child\"`\n" +
+				"\tId int64 `json:\"id\"`\n" +
+				"}\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tree, err := Parse(testCase.Source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree.Name = testCase.Name
+		tree.DetectRecursion()
+
+		formatted, err := tree.Format()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(formatted) != testCase.Expected {
+			t.Errorf("Source: %s Expected: %q Got: %q", testCase.Source, testCase.Expected, formatted)
+		}
+	}
+}
+
+// The values of every level are observed by the struct referred to.
+func TestRecursionStats(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.enum = true
+		c.enumMax = 10
+		c.enumSamples = 1
+	})()
+
+	tree, err := Parse(`{"name": "root", "children": [{"name": "a", "children": [{"name": "b"}]}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree.DetectRecursion()
+
+	expected := "type _ Node\n" +
+		"\n" +
+		"type Name string\n" +
+		"\n" +
+		"const (\n" +
+		"\tNameA    Name = \"a\"\n" +
+		"\tNameB    Name = \"b\"\n" +
+		"\tNameRoot Name = \"root\"\n" +
+		")\n" +
+		"\n" +
+		"type Node struct {\n" +
+		"\tChildren []Node `json:\"children\"`\n" +
+		"\tName     Name   `json:\"name\"`\n" +
+		"}\n"
+
+	formatted, err := tree.Format()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(formatted) != expected {
+		t.Errorf("Expected: %q Got: %q", expected, formatted)
+	}
+
+	// Children are missing from one of the three objects.
+	if children := tree.Children[0]; !children.optional(&tree) {
+		t.Errorf("Expected: optional children Got: %+v", children.Stats)
+	}
+}
+
+// Recursive types refer to their struct by path, so they survive being dumped
+// and loaded.
+func TestRecursionDump(t *testing.T) {
+	tree, err := Parse(`{"category": {"name": "a", "children": [{"name": "b", "children": []}]}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree.DetectRecursion()
+
+	dumped, err := json.Marshal(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var loaded Tree
+	if err := json.Unmarshal(dumped, &loaded); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(loaded, tree) {
+		t.Errorf("Expected: %+v Got: %+v", tree, loaded)
+	}
+
+	children := loaded.Children[0].Children[0]
+	if children.Type != Recursive || children.Ref != ".category" || !children.List {
+		t.Errorf("Expected: []recursive .category Got: %+v", children)
+	}
+}
+
+func TestValidateRecursion(t *testing.T) {
+	tree, err := Parse(`{"name": "a", "children": [{"name": "b", "children": []}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree.DetectRecursion()
+
+	testCases := []struct {
+		Document string
+		Errors   []ValidationError
+	}{
+		{`{"name": "a", "children": [{"name": "b", "children": [{"name": "c", "children": [{"name": "d", "children": []}]}]}]}`, nil},
+		{`{"name": "a", "children": [{"name": "b", "children": [{"name": 1, "children": []}]}]}`, []ValidationError{
+			{".children[0].children[0].name", TypeMismatch, "expected string, got number"},
+		}},
+	}
+
+	for _, testCase := range testCases {
+		var data interface{}
+		jsonDecoder := json.NewDecoder(bytes.NewBufferString(testCase.Document))
+		jsonDecoder.UseNumber()
+		if err := jsonDecoder.Decode(&data); err != nil {
+			t.Fatal(err)
+		}
+
+		if errs := tree.Validate(data); !reflect.DeepEqual(errs, testCase.Errors) {
+			t.Errorf("Document: %s Expected: %+v Got: %+v", testCase.Document, testCase.Errors, errs)
+		}
+	}
+}
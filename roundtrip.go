@@ -0,0 +1,218 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Reads the samples embedded in a round trip test: the named files, or
+// standard input if there are none. Standard input is replaced by what was
+// read so it can still be inferred from.
+func readSamples(filenames []string) (samples []string, err error) {
+	if len(filenames) == 0 {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, err
+		}
+		stdin = bytes.NewReader(data)
+		return []string{string(data)}, nil
+	}
+
+	for _, filename := range filenames {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, string(data))
+	}
+	return samples, nil
+}
+
+// Writes a test checking each sample decodes into the named type with
+// unknown fields disallowed and encodes back to equal JSON. The test is in
+// the package of the other Go files in its directory, main if there are
+// none.
+func writeTest(filename, name string, samples []string) error {
+	pkg, err := packageName(filepath.Dir(filename))
+	if err != nil {
+		return err
+	}
+
+	src, err := roundTripTest(pkg, name, samples)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, src, 0644)
+}
+
+// Returns the name of the package of the Go files in a directory, main if
+// there are none.
+func packageName(dir string) (string, error) {
+	filenames, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return "", err
+	}
+
+	for _, filename := range filenames {
+		if strings.HasSuffix(filename, "_test.go") {
+			continue
+		}
+
+		// Files which aren't Go source are skipped.
+		file, err := parser.ParseFile(token.NewFileSet(), filename, nil, parser.PackageClauseOnly)
+		if err == nil {
+			return file.Name.Name, nil
+		}
+	}
+	return "main", nil
+}
+
+// Returns the source of a round trip test of a type.
+func roundTripTest(pkg, name string, samples []string) ([]byte, error) {
+	prefix := unexported(name)
+
+	src := "package " + pkg + "\n\n"
+	src += "import (\n\t\"encoding/json\"\n\t\"reflect\"\n\t\"strings\"\n\t\"testing\"\n)\n\n"
+
+	src += "// Samples " + name + " was generated from.\n"
+	src += "var " + prefix + "Samples = []string{\n"
+	for _, sample := range samples {
+		src += "\t" + stringLiteral(strings.TrimSpace(sample)) + ",\n"
+	}
+	src += "}\n\n"
+
+	src += "// Tests each sample decodes into " + name + " with unknown fields disallowed and\n"
+	src += "// encodes back to equal JSON. Fields missing from a sample, or null, may be\n"
+	src += "// encoded as zero values.\n"
+	src += "func Test" + name + "RoundTrip(t *testing.T) {\n"
+	src += `	var zero func(v interface{}) bool
+	zero = func(v interface{}) bool {
+		switch v := v.(type) {
+		case nil:
+			return true
+		case bool:
+			return !v
+		case float64:
+			return v == 0
+		case string:
+			// Including time.Time's zero value.
+			return v == "" || v == "0001-01-01T00:00:00Z"
+		case []interface{}:
+			return len(v) == 0
+		case map[string]interface{}:
+			for _, value := range v {
+				if !zero(value) {
+					return false
+				}
+			}
+			return true
+		}
+		return false
+	}
+
+	var equal func(sample, encoded interface{}) bool
+	equal = func(sample, encoded interface{}) bool {
+		switch sample := sample.(type) {
+		case nil:
+			return zero(encoded)
+		case []interface{}:
+			list, ok := encoded.([]interface{})
+			if len(sample) == 0 {
+				return zero(encoded)
+			}
+			if !ok || len(list) != len(sample) {
+				return false
+			}
+			for idx := range sample {
+				if !equal(sample[idx], list[idx]) {
+					return false
+				}
+			}
+			return true
+		case map[string]interface{}:
+			object, ok := encoded.(map[string]interface{})
+			if !ok {
+				return false
+			}
+			for key := range sample {
+				if _, ok := object[key]; !ok {
+					return false
+				}
+			}
+			for key, value := range object {
+				if !equal(sample[key], value) {
+					return false
+				}
+			}
+			return true
+		}
+		return reflect.DeepEqual(sample, encoded)
+	}
+
+`
+	src += "\tfor idx, sample := range " + prefix + "Samples {\n"
+	src += `		decoder := json.NewDecoder(strings.NewReader(sample))
+		decoder.DisallowUnknownFields()
+
+`
+	src += "\t\tvar v " + name + "\n"
+	src += `		if err := decoder.Decode(&v); err != nil {
+			t.Errorf("Sample %d: decoding: %s", idx, err)
+			continue
+		}
+
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			t.Errorf("Sample %d: encoding: %s", idx, err)
+			continue
+		}
+
+		var expected, got interface{}
+		if err := json.Unmarshal([]byte(sample), &expected); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(encoded, &got); err != nil {
+			t.Fatal(err)
+		}
+		if !equal(expected, got) {
+			t.Errorf("Sample %d: Expected: %s Got: %s", idx, sample, encoded)
+		}
+	}
+}
+`
+
+	return format.Source([]byte(src))
+}
+
+// Returns a Go string literal, raw unless the string holds a backtick or a
+// carriage return.
+func stringLiteral(s string) string {
+	if strings.ContainsAny(s, "`\r") || !utf8.ValidString(s) {
+		return strconv.Quote(s)
+	}
+	return "`" + s + "`"
+}
@@ -0,0 +1,132 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRoundTripTest(t *testing.T) {
+	samples := []string{"{\"a\": 1}\n", "{\"b\": \"`\"}"}
+
+	src, err := roundTripTest("api", "Order", samples)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := parser.ParseFile(token.NewFileSet(), "order_test.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Name.Name != "api" {
+		t.Errorf("Expected: %q Got: %q", "api", file.Name.Name)
+	}
+
+	// Samples are embedded in order, as string literals.
+	var embedded []string
+	var funcs []string
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				value, ok := spec.(*ast.ValueSpec)
+				if !ok || value.Names[0].Name != "orderSamples" {
+					continue
+				}
+				for _, elt := range value.Values[0].(*ast.CompositeLit).Elts {
+					s, err := strconv.Unquote(elt.(*ast.BasicLit).Value)
+					if err != nil {
+						t.Fatal(err)
+					}
+					embedded = append(embedded, s)
+				}
+			}
+		case *ast.FuncDecl:
+			funcs = append(funcs, decl.Name.Name)
+		}
+	}
+
+	expected := []string{`{"a": 1}`, "{\"b\": \"`\"}"}
+	if strings.Join(embedded, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected: %q Got: %q", expected, embedded)
+	}
+	if strings.Join(funcs, ",") != "TestOrderRoundTrip" {
+		t.Errorf("Expected: %q Got: %q", "TestOrderRoundTrip", funcs)
+	}
+	if !strings.Contains(string(src), "var v Order\n") || !strings.Contains(string(src), "decoder.DisallowUnknownFields()") {
+		t.Errorf("Expected a strict decode of Order Got: %s", src)
+	}
+}
+
+func TestPackageName(t *testing.T) {
+	dir := t.TempDir()
+
+	name, err := packageName(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "main" {
+		t.Errorf("Expected: %q Got: %q", "main", name)
+	}
+
+	files := map[string]string{
+		"a_test.go": "package api_test\n",
+		"b.go":      "package api\n",
+	}
+	for filename, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, filename), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if name, err = packageName(dir); err != nil {
+		t.Fatal(err)
+	}
+	if name != "api" {
+		t.Errorf("Expected: %q Got: %q", "api", name)
+	}
+}
+
+// Standard input is still inferred from once it's read.
+func TestReadSamplesStdin(t *testing.T) {
+	saved := stdin
+	defer func() { stdin = saved }()
+	stdin = strings.NewReader(`{"a": 1}`)
+
+	samples, err := readSamples(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 1 || samples[0] != `{"a": 1}` {
+		t.Errorf("Expected: %q Got: %q", `{"a": 1}`, samples)
+	}
+
+	var tree Tree
+	if err := InferFiles(&tree, nil, 1); err != nil {
+		t.Fatal(err)
+	}
+	if len(tree.Children) != 1 || tree.Children[0].Name != "a" {
+		t.Errorf("Expected: field a Got: %v", tree.Children)
+	}
+}
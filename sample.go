@@ -0,0 +1,178 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strconv"
+)
+
+// Number of times a recursive type is expanded in a sample.
+const sampleDepth = 2
+
+// Returns a document which decodes into the type described by the tree, with
+// placeholder values chosen from the stats of the tree where there are any.
+// Fields missing from some of the objects observed are only included if
+// optional is set, lists have length elements.
+func (t *Tree) Sample(optional bool, length int) interface{} {
+	s := &sampler{
+		optional: optional,
+		length:   length,
+		targets:  t.targets(),
+		depth:    make(map[string]int),
+	}
+	return s.value(t, t.List, 0)
+}
+
+type sampler struct {
+	optional bool
+	length   int
+
+	// Structs recursive types refer to by path and the number of times each
+	// is being expanded.
+	targets map[string]*Tree
+	depth   map[string]int
+}
+
+// Returns a value of the tree's type, the idx'th if it's an element of a list.
+func (s *sampler) value(t *Tree, list bool, idx int) interface{} {
+	// Recursive types expand the struct they refer to until they're too
+	// deep, then they're empty.
+	if t.Type == Recursive {
+		target, ok := s.targets[t.Ref]
+		if !ok || s.depth[t.Ref] >= sampleDepth {
+			if list {
+				return []interface{}{}
+			}
+			return nil
+		}
+
+		s.depth[t.Ref]++
+		defer func() { s.depth[t.Ref]-- }()
+		return s.value(target, list, idx)
+	}
+
+	if list {
+		elements := make([]interface{}, s.length)
+		for idx := range elements {
+			elements[idx] = s.value(t, false, idx)
+		}
+		return elements
+	}
+
+	switch t.Type {
+	case Bool:
+		return idx%2 == 0
+	case Int:
+		return s.integer(t, idx)
+	case Float:
+		return json.Number(strconv.FormatFloat(float64(idx)+0.5, 'f', -1, 64))
+	case String:
+		return s.string(t, idx)
+	case Struct:
+		return s.object(t, t, idx)
+	case Tuple:
+		var positions []interface{}
+		for _, position := range t.Children {
+			positions = append(positions, s.value(position, position.List, idx))
+		}
+		return positions
+	case Union:
+		// Elements of lists cycle through the variants.
+		variant := t.Children[idx%len(t.Children)]
+		object := s.object(variant, variant, idx)
+		object[string(t.Discriminator)] = string(variant.Name)
+		return object
+	}
+
+	return nil
+}
+
+// Returns an object with a value for each field of a struct, the idx'th if
+// it's an element of a list.
+func (s *sampler) object(t, parent *Tree, idx int) map[string]interface{} {
+	object := make(map[string]interface{})
+	for _, child := range t.Children {
+		if !s.optional && child.optional(parent) {
+			continue
+		}
+		object[string(child.Name)] = s.value(child, child.List, idx)
+	}
+	return object
+}
+
+// Returns an integer in the observed range, the minimum for the first element
+// of a list and the maximum for the rest.
+func (s *sampler) integer(t *Tree, idx int) interface{} {
+	stats := t.values()
+	if stats == nil || stats.Min == nil {
+		return idx + 1
+	}
+
+	if idx == 0 {
+		return json.Number(stats.Min.String())
+	}
+	return json.Number(stats.Max.String())
+}
+
+// Returns a string like those observed: an observed value if there were few
+// of them, a value of the detected kind, base64, a number or bool for quoted
+// values or the field's name.
+func (s *sampler) string(t *Tree, idx int) string {
+	stats := t.values()
+	if stats != nil && len(stats.Values) > 0 && !stats.Overflow {
+		var values []string
+		for v := range stats.Values {
+			values = append(values, v)
+		}
+		sort.Strings(values)
+		return values[idx%len(values)]
+	}
+
+	if d := t.detector(); d != nil {
+		return d.Sample
+	}
+
+	if t.base64() {
+		return base64.StdEncoding.EncodeToString([]byte("sample " + strconv.Itoa(idx+1)))
+	}
+
+	if stats != nil && stats.Strings > 0 {
+		switch stats.Strings {
+		case stats.IntStrings:
+			return strconv.Itoa(idx + 1)
+		case stats.FloatStrings:
+			return strconv.FormatFloat(float64(idx)+0.5, 'f', -1, 64)
+		case stats.BoolStrings:
+			return strconv.FormatBool(idx%2 == 0)
+		}
+	}
+
+	if t.Name == "" {
+		return "string"
+	}
+	return string(t.Name)
+}
+
+// Returns true if a field may be omitted, or was missing from some of the
+// objects of its parent which were observed.
+func (t *Tree) optional(parent *Tree) bool {
+	s, p := t.Stats, parent.values()
+	return t.Optional || s != nil && p != nil && s.Count < p.Count
+}
@@ -0,0 +1,131 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestSample(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.command = "sample"
+	})()
+
+	testCases := []struct {
+		Source   string
+		Optional bool
+		Length   int
+		Expected string
+	}{
+		{`{"a": 1, "b": "foo", "c": true, "d": 1.5, "e": null}`, true, 2,
+			`{"a":1,"b":"b","c":true,"d":0.5,"e":null}`},
+		{`[3, 7, 5]`, true, 3, `[3,7,7]`},
+		{`[{"a": 1, "b": 2}, {"a": 3}]`, true, 1, `[{"a":1,"b":2}]`},
+		// Fields missing from some objects can be left out.
+		{`[{"a": 1, "b": 2}, {"a": 3}]`, false, 1, `[{"a":1}]`},
+		{`{"a": [true, false], "b": []}`, true, 2, `{"a":[true,false],"b":[null,null]}`},
+		{`{"a": ["1", "2"]}`, true, 2, `{"a":["1","2"]}`},
+		{`{"a": {"b": [{"c": "x"}]}}`, true, 0, `{"a":{"b":[]}}`},
+	}
+
+	for _, testCase := range testCases {
+		tree, err := Parse(testCase.Source)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sample, err := json.Marshal(tree.Sample(testCase.Optional, testCase.Length))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(sample) != testCase.Expected {
+			t.Errorf("Source: %s Expected: %q Got: %q", testCase.Source, testCase.Expected, sample)
+		}
+	}
+}
+
+// Samples use the values observed by options which collect them, or the
+// sample of the detector which matched when there were too many.
+func TestSampleValues(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.command = "sample"
+		c.enum = true
+		c.enumMax = 4
+		c.detectors = map[string]bool{"email": true}
+	})()
+
+	tree, err := Parse(`[{"state": "on", "to": "a@example.org"}, {"state": "off", "to": "b@example.org"}, {"to": "c@example.org"}, {"to": "d@example.org"}, {"to": "e@example.org"}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sample, err := json.Marshal(tree.Sample(true, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `[{"state":"off","to":"user@example.com"},{"state":"on","to":"user@example.com"}]`
+	if string(sample) != expected {
+		t.Errorf("Expected: %q Got: %q", expected, sample)
+	}
+}
+
+// Samples of tuples, unions and recursive types are valid documents of the
+// tree they were generated from.
+func TestSampleValidates(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.command = "sample"
+		c.tuples = true
+		c.tupleMax = 8
+		c.unions = true
+		c.unionMax = 16
+	})()
+
+	testCases := []string{
+		`{"a": [1, "foo", true]}`,
+		`{"events": [{"type": "click", "x": 1}, {"type": "key", "code": "a"}]}`,
+		`{"name": "a", "children": [{"name": "b", "children": [{"name": "c", "children": []}]}]}`,
+		`[{"a": 1, "b": 2}, {"a": 3}]`,
+	}
+
+	for _, source := range testCases {
+		tree, err := Parse(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree.DetectRecursion()
+
+		sample, err := json.Marshal(tree.Sample(true, 2))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var v interface{}
+		decoder := json.NewDecoder(bytes.NewReader(sample))
+		decoder.UseNumber()
+		if err := decoder.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+
+		if errs := tree.Validate(v); len(errs) > 0 {
+			t.Errorf("Source: %s Sample: %s Got: %v", source, sample, errs)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Returns true if a tree should be loaded from Go source rather than a dump:
+// a Go file or a package directory.
+func isSource(filename string) bool {
+	if strings.HasSuffix(filename, ".go") {
+		return true
+	}
+	info, err := os.Stat(filename)
+	return err == nil && info.IsDir()
+}
+
+// Loads a tree describing the JSON encoding of a struct type declared in a Go
+// file or the package in a directory. Fields are named by their json tag,
+// fields tagged "-" and unexported fields are left out and the fields of
+// embedded structs are promoted as encoding/json does. Fields with the
+// omitempty option are optional and pointers are nullable. If typeName is
+// empty the first struct type declared is loaded.
+func LoadSource(filename, typeName string, t *Tree) error {
+	fset := token.NewFileSet()
+
+	var files []*ast.File
+	if strings.HasSuffix(filename, ".go") {
+		file, err := parser.ParseFile(fset, filename, nil, 0)
+		if err != nil {
+			return err
+		}
+		files = append(files, file)
+	} else {
+		pkgs, err := parser.ParseDir(fset, filename, func(info os.FileInfo) bool {
+			return !strings.HasSuffix(info.Name(), "_test.go")
+		}, 0)
+		if err != nil {
+			return err
+		}
+
+		// Sort packages and files for consistent results.
+		var names []string
+		for name := range pkgs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var filenames []string
+		for _, name := range names {
+			for filename := range pkgs[name].Files {
+				filenames = append(filenames, filename)
+			}
+		}
+		sort.Strings(filenames)
+
+		for _, filename := range filenames {
+			for _, pkg := range pkgs {
+				if file, ok := pkg.Files[filename]; ok {
+					files = append(files, file)
+				}
+			}
+		}
+	}
+
+	if len(files) == 0 {
+		return fmt.Errorf("no Go source files in %s", filename)
+	}
+
+	// Type errors are tolerated, e.g. from packages which can't be imported,
+	// types which can't be resolved are the empty interface.
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Error:    func(error) {},
+	}
+	info := &types.Info{Defs: make(map[*ast.Ident]types.Object)}
+	pkg, _ := conf.Check(files[0].Name.Name, fset, files, info)
+
+	// Find the type in the order it's declared.
+	var named *types.Named
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				obj, ok := info.Defs[ts.Name].(*types.TypeName)
+				if named != nil || !ok || typeName != "" && typeName != ts.Name.Name {
+					continue
+				}
+				if n, ok := obj.Type().(*types.Named); ok {
+					if _, ok := n.Underlying().(*types.Struct); ok {
+						named = n
+					}
+				}
+			}
+		}
+	}
+
+	if named == nil {
+		if typeName == "" {
+			return fmt.Errorf("no struct type declared in %s", filename)
+		}
+		return fmt.Errorf("no struct type %q declared in %s", typeName, filename)
+	}
+
+	c := converter{pkg: pkg, stack: make(map[*types.Named]string)}
+	c.convert(t, named, ".")
+	return nil
+}
+
+// Converts Go types to trees.
+type converter struct {
+	// Package the types are declared in, whose types are referred to
+	// unqualified.
+	pkg *types.Package
+
+	// Paths of the named structs being converted, types which contain
+	// themselves are recursive.
+	stack map[*types.Named]string
+}
+
+// Describes the JSON encoding of a type, t is at path.
+func (c *converter) convert(t *Tree, typ types.Type, path string) {
+	// Aliases are known by their own name, e.g.: encoding/json.RawMessage.
+	if alias, ok := typ.(*types.Alias); ok {
+		if c.formatted(t, alias.Obj()) || c.known(t, alias.Obj()) {
+			return
+		}
+		typ = types.Unalias(alias)
+	}
+
+	if named, ok := typ.(*types.Named); ok {
+		if c.formatted(t, named.Obj()) || c.known(t, named.Obj()) || c.custom(t, named) {
+			return
+		}
+
+		if _, ok := named.Underlying().(*types.Struct); ok {
+			if ref, ok := c.stack[named]; ok {
+				t.Type = Recursive
+				t.Ref = ref
+				return
+			}
+
+			c.stack[named] = path
+			defer delete(c.stack, named)
+		}
+	}
+
+	switch u := typ.Underlying().(type) {
+	case *types.Basic:
+		switch info := u.Info(); {
+		case info&types.IsBoolean != 0:
+			t.Type = Bool
+		case info&types.IsInteger != 0:
+			t.Type = Int
+		case info&types.IsFloat != 0:
+			t.Type = Float
+		case info&types.IsString != 0:
+			t.Type = String
+		default:
+			t.Type = Interface
+		}
+	case *types.Pointer:
+		c.convert(t, u.Elem(), path)
+		t.Nullable = true
+	case *types.Slice:
+		// Byte slices are encoded as base64 strings.
+		if b, ok := u.Elem().(*types.Basic); ok && b.Kind() == types.Byte {
+			t.Type = String
+			return
+		}
+		c.list(t, u.Elem(), path)
+	case *types.Array:
+		c.list(t, u.Elem(), path)
+	case *types.Struct:
+		t.Type = Struct
+		c.fields(t, u, path)
+		sort.Sort(t)
+	default:
+		// Maps, interfaces and types encoding/json can't encode.
+		t.Type = Interface
+	}
+}
+
+// Describes types which are the type of a detector as strings of its format,
+// e.g.: time.Time or net/netip.Addr. Returns false for other types.
+func (c *converter) formatted(t *Tree, obj *types.TypeName) bool {
+	name := obj.Name()
+	if obj.Pkg() != nil && obj.Pkg() != c.pkg {
+		name = obj.Pkg().Path() + "." + name
+	}
+
+	for _, d := range detectors {
+		if d.goType() == name || d.Type == name {
+			t.Type = String
+			t.Detector = d.Name
+			return true
+		}
+	}
+	return false
+}
+
+// Describes types from the standard library whose encoding isn't derived
+// from their declaration. Returns false for other types.
+func (c *converter) known(t *Tree, obj *types.TypeName) bool {
+	if obj.Pkg() == nil {
+		return false
+	}
+
+	switch obj.Pkg().Path() + "." + obj.Name() {
+	case "math/big.Int":
+		t.Type = Int
+	case "math/big.Float", "encoding/json.Number":
+		t.Type = Float
+	default:
+		return false
+	}
+	return true
+}
+
+// Describes types which decode themselves, from JSON values which could be
+// anything or from strings. Returns false for other types.
+func (c *converter) custom(t *Tree, named *types.Named) bool {
+	methods := types.NewMethodSet(types.NewPointer(named))
+	switch {
+	case methods.Lookup(nil, "UnmarshalJSON") != nil:
+		t.Type = Interface
+	case methods.Lookup(nil, "UnmarshalText") != nil:
+		t.Type = String
+	default:
+		return false
+	}
+	return true
+}
+
+// Describes a slice or array. Trees can't describe lists of lists, so they're
+// lists of the empty interface.
+func (c *converter) list(t *Tree, elem types.Type, path string) {
+	c.convert(t, elem, path)
+	if t.List {
+		t.Type = Interface
+		t.Children = nil
+		t.Ref = ""
+	}
+	t.List = true
+	t.Nullable = false
+}
+
+// Adds the fields of a struct to a tree, including the fields promoted from
+// embedded structs. Fields declared in a struct hide promoted fields of the
+// same name.
+func (c *converter) fields(t *Tree, s *types.Struct, path string) {
+	declared := make(map[Ident]bool)
+	var promoted []*Tree
+
+	for idx := 0; idx < s.NumFields(); idx++ {
+		field := s.Field(idx)
+
+		tag := reflect.StructTag(s.Tag(idx)).Get("json")
+		if tag == "-" {
+			continue
+		}
+		options := strings.Split(tag, ",")
+		name := options[0]
+
+		if field.Anonymous() && name == "" {
+			typ := types.Unalias(field.Type())
+			pointer, isPointer := typ.(*types.Pointer)
+			if isPointer {
+				typ = types.Unalias(pointer.Elem())
+			}
+
+			if _, ok := typ.Underlying().(*types.Struct); ok {
+				embedded := &Tree{}
+				c.convert(embedded, typ, path)
+				for _, child := range embedded.Children {
+					// Fields of nil embedded pointers are omitted.
+					child.Optional = child.Optional || isPointer
+					promoted = append(promoted, child)
+				}
+				continue
+			}
+		}
+
+		if !field.Exported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name()
+		}
+
+		child := &Tree{Name: Ident(name)}
+		c.convert(child, field.Type(), joinPath(path, name))
+		for _, option := range options[1:] {
+			switch option {
+			case "omitempty", "omitzero":
+				child.Optional = true
+			case "string":
+				// Only applies to numbers and bools.
+				if !child.List && (child.Type == Bool || child.Type == Int || child.Type == Float) {
+					child.Type = String
+				}
+			}
+		}
+
+		declared[child.Name] = true
+		t.Children = append(t.Children, child)
+	}
+
+	for _, child := range promoted {
+		if !declared[child.Name] {
+			declared[child.Name] = true
+			t.Children = append(t.Children, child)
+		}
+	}
+}
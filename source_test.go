@@ -0,0 +1,199 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const sourceTypes = `package api
+
+import (
+	"net/netip"
+	"time"
+)
+
+type Base struct {
+	ID      int64     ` + "`json:\"id\"`" + `
+	Created time.Time ` + "`json:\"created\"`" + `
+}
+
+type Meta struct {
+	Tags []string ` + "`json:\"tags,omitempty\"`" + `
+}
+
+type Category struct {
+	Base
+	*Meta
+	Name     string     ` + "`json:\"name\"`" + `
+	Secret   string     ` + "`json:\"-\"`" + `
+	hidden   int
+	Parent   *Category  ` + "`json:\"parent,omitempty\"`" + `
+	Children []Category ` + "`json:\"children\"`" + `
+	Count    int        ` + "`json:\"count,string\"`" + `
+	Data     []byte
+	Attrs    map[string]int
+	Grid     [][]float64
+	Host     netip.Addr ` + "`json:\"host\"`" + `
+}
+`
+
+// Writes source to a file in a temporary directory, returning the file's
+// name and a function removing the directory.
+func writeSource(t *testing.T, src string) (string, func()) {
+	dir, err := ioutil.TempDir("", "jsongen")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join(dir, "types.go")
+	if err := ioutil.WriteFile(filename, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename, func() { os.RemoveAll(dir) }
+}
+
+func TestLoadSource(t *testing.T) {
+	filename, cleanup := writeSource(t, sourceTypes)
+	defer cleanup()
+
+	expected := Tree{
+		Type: Struct,
+		Children: []*Tree{
+			{Name: "Attrs", Type: Interface},
+			{Name: "children", List: true, Type: Recursive, Ref: "."},
+			{Name: "count", Type: String},
+			{Name: "created", Type: String, Detector: "time"},
+			{Name: "Data", Type: String},
+			{Name: "Grid", List: true, Type: Interface},
+			{Name: "host", Type: String, Detector: "ip"},
+			{Name: "id", Type: Int},
+			{Name: "name", Type: String},
+			{Name: "parent", Type: Recursive, Ref: ".", Optional: true, Nullable: true},
+			{Name: "tags", List: true, Type: String, Optional: true},
+		},
+	}
+
+	// Both the file and the package directory can be loaded.
+	for _, source := range []string{filename, filepath.Dir(filename)} {
+		var tree Tree
+		if err := LoadSource(source, "Category", &tree); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(tree, expected) {
+			t.Errorf("Expected: %+v Got: %+v", expected, tree)
+		}
+	}
+}
+
+// Without a name the first struct type declared is loaded.
+func TestLoadSourceFirst(t *testing.T) {
+	filename, cleanup := writeSource(t, sourceTypes)
+	defer cleanup()
+
+	var tree Tree
+	if err := LoadSource(filename, "", &tree); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Tree{
+		Type: Struct,
+		Children: []*Tree{
+			{Name: "created", Type: String, Detector: "time"},
+			{Name: "id", Type: Int},
+		},
+	}
+	if !reflect.DeepEqual(tree, expected) {
+		t.Errorf("Expected: %+v Got: %+v", expected, tree)
+	}
+
+	if err := LoadSource(filename, "Missing", &tree); err == nil {
+		t.Errorf("Expected error loading missing type")
+	}
+}
+
+// Optional fields may be missing and nullable fields null.
+func TestValidateSource(t *testing.T) {
+	filename, cleanup := writeSource(t, sourceTypes)
+	defer cleanup()
+
+	var tree Tree
+	if err := LoadSource(filename, "Category", &tree); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		Source   string
+		Expected []ValidationError
+	}{
+		{`{"Attrs": {}, "children": [], "count": "1", "created": "2021-04-05T10:00:00Z", "Data": "", "Grid": [], "host": "::1", "id": 1, "name": "", "parent": null}`, nil},
+		{`{"Attrs": {}, "children": [], "count": "1", "created": "2021-04-05T10:00:00Z", "Data": "", "Grid": [], "host": "::1", "id": 1, "name": null}`,
+			[]ValidationError{{".name", NullValue, "expected string"}}},
+		// Strings of types with a format must match it.
+		{`{"Attrs": {}, "children": [], "count": "1", "created": "created", "Data": "", "Grid": [], "host": "localhost", "id": 1, "name": ""}`,
+			[]ValidationError{
+				{".created", TypeMismatch, `expected RFC 3339 timestamp, got "created"`},
+				{".host", TypeMismatch, `expected IP address, got "localhost"`},
+			}},
+	}
+
+	for _, testCase := range testCases {
+		var document interface{}
+		jsonDecoder := json.NewDecoder(bytes.NewBufferString(testCase.Source))
+		jsonDecoder.UseNumber()
+		if err := jsonDecoder.Decode(&document); err != nil {
+			t.Fatal(err)
+		}
+
+		if errs := tree.Validate(document); !reflect.DeepEqual(errs, testCase.Expected) {
+			t.Errorf("Source: %s Expected: %v Got: %v", testCase.Source, testCase.Expected, errs)
+		}
+	}
+}
+
+// Samples of types with a format decode into them.
+func TestSampleSource(t *testing.T) {
+	filename, cleanup := writeSource(t, sourceTypes)
+	defer cleanup()
+
+	var tree Tree
+	if err := LoadSource(filename, "Category", &tree); err != nil {
+		t.Fatal(err)
+	}
+
+	sample, err := json.Marshal(tree.Sample(true, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v struct {
+		Created time.Time  `json:"created"`
+		Host    netip.Addr `json:"host"`
+	}
+	if err := json.Unmarshal(sample, &v); err != nil {
+		t.Errorf("Expected: a sample decoding into time.Time and netip.Addr Got: %s %s", err, sample)
+	}
+}
@@ -0,0 +1,230 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Column types of each SQL dialect, by the kind of value they hold. Numerics
+// are integers beyond the range of int64, keys are the primary keys of
+// tables.
+var sqlDialects = map[string]map[string]string{
+	"postgres": {
+		"bool":    "BOOLEAN",
+		"int":     "BIGINT",
+		"numeric": "NUMERIC",
+		"float":   "DOUBLE PRECISION",
+		"string":  "TEXT",
+		"time":    "TIMESTAMPTZ",
+		"bytes":   "BYTEA",
+		"json":    "JSONB",
+		"key":     "BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY",
+		"ref":     "BIGINT",
+	},
+	"sqlite": {
+		"bool":    "BOOLEAN",
+		"int":     "INTEGER",
+		"numeric": "TEXT",
+		"float":   "REAL",
+		"string":  "TEXT",
+		"time":    "TEXT",
+		"bytes":   "BLOB",
+		"json":    "TEXT",
+		"key":     "INTEGER PRIMARY KEY",
+		"ref":     "INTEGER",
+	},
+}
+
+// Returns the names of SQL dialects for usage.
+func sqlDialectNames() string {
+	var names []string
+	for name := range sqlDialects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// Kinds of scalar columns of values observed as a single JSON kind and null.
+var sqlKinds = map[string]string{"bool": "bool", "number": "float", "string": "string"}
+
+// Names of the primary key of every table and the foreign key of child
+// tables.
+const (
+	sqlKey       = "row_id"
+	sqlParentKey = "parent_row_id"
+)
+
+func init() {
+	RegisterEmitter("sql", sqlDDL{})
+}
+
+// Emits CREATE TABLE statements: a table for the top level objects with a
+// column for each scalar field, nested objects flattened into columns
+// prefixed with their field's name and a child table for each list of
+// objects, with a foreign key to its parent. Other values are JSON columns.
+type sqlDDL struct{}
+
+func (sqlDDL) Emit(g *Graph) ([]byte, []string, error) {
+	w := &sqlWriter{types: sqlDialects[config.sqlDialect], names: newNamer("_"), path: "."}
+
+	// Each top level object, or each element of a top level list of them,
+	// is a row. Anything else is a row holding the value.
+	r := g.Root
+	if r.Decl != nil && !r.Recursive && mapValue(r.Decl) == nil {
+		w.table(protoName(g.Name), r.Decl, "")
+	} else {
+		w.note("top level type isn't an object, declared as column value")
+		w.table(protoName(g.Name), &Decl{Type: Struct, Fields: []*Field{{Name: "Value", Key: "value", Type: r}}}, "")
+	}
+
+	var statements []string
+	for _, t := range w.tables {
+		statements = append(statements, "CREATE TABLE "+sqlQuote(t.name)+" (\n"+strings.Join(t.columns, ",\n")+"\n);\n")
+	}
+	return []byte(strings.Join(statements, "\n")), w.diagnostics, nil
+}
+
+// A table and the definitions of its columns.
+type sqlTable struct {
+	name    string
+	columns []string
+	names   *namer
+}
+
+// Declares the tables a type graph requires, parents before their children.
+type sqlWriter struct {
+	types  map[string]string
+	tables []*sqlTable
+	names  *namer
+
+	// Path of the field being declared.
+	path        string
+	diagnostics []string
+}
+
+// Records a note about the field being declared.
+func (w *sqlWriter) note(format string, args ...interface{}) {
+	w.diagnostics = append(w.diagnostics, w.path+": "+fmt.Sprintf(format, args...))
+}
+
+// Declares a table for the objects of a struct or union, with a foreign key
+// to its parent table if it has one.
+func (w *sqlWriter) table(name string, d *Decl, parent string) {
+	t := &sqlTable{name: w.names.declare(name), names: newNamer("_")}
+	w.tables = append(w.tables, t)
+
+	t.column(sqlKey, w.types["key"], nil)
+	if parent != "" {
+		t.column(sqlParentKey, w.types["ref"]+" NOT NULL REFERENCES "+sqlQuote(parent)+" ("+sqlQuote(sqlKey)+")", nil)
+	}
+	w.columns(t, d, "", false)
+}
+
+// Declares the columns of the fields of a struct or union, prefixed with the
+// names of the fields it's nested in. Nested columns are nullable if any
+// field they're nested in is.
+func (w *sqlWriter) columns(t *sqlTable, d *Decl, prefix string, nullable bool) {
+	fields := d.Fields
+	if d.Type == Union {
+		fields = variantFields(d)
+	}
+
+	for _, field := range fields {
+		r := field.Type
+		name := prefix + protoName(field.Key)
+		null := nullable || field.Optional || r.Nullable
+
+		path := w.path
+		w.path = joinPath(path, field.Key)
+
+		switch {
+		case r.Decl == nil || mapValue(r.Decl) != nil:
+			w.column(t, name, r, null, field.Doc)
+		case r.Recursive:
+			w.note("recursive object declared as a JSON column")
+			w.column(t, name, r, null, field.Doc)
+		case r.List:
+			w.table(t.name+"_"+name, r.Decl, t.name)
+		default:
+			w.columns(t, r.Decl, name+"_", null)
+		}
+
+		w.path = path
+	}
+}
+
+// Declares a column holding the values of a reference.
+func (w *sqlWriter) column(t *sqlTable, name string, r *Ref, nullable bool, doc []string) {
+	kind := "json"
+	switch {
+	case r.List:
+	case r.Type == Bool:
+		kind = "bool"
+	case r.Type == Int && r.big():
+		kind = "numeric"
+	case r.Type == Int:
+		kind = "int"
+	case r.Type == Float:
+		kind = "float"
+	case r.Type == String:
+		kind = "string"
+		if r.Format == "time" {
+			kind = "time"
+		} else if r.Bytes {
+			kind = "bytes"
+		}
+	case r.Type == Interface:
+		// Values of a single kind besides null are columns of that kind.
+		var kinds []string
+		for _, k := range r.Kinds {
+			if k == "null" {
+				nullable = true
+			} else {
+				kinds = append(kinds, k)
+			}
+		}
+		if len(kinds) == 1 && sqlKinds[kinds[0]] != "" {
+			kind = sqlKinds[kinds[0]]
+		}
+	}
+
+	typ := w.types[kind]
+	if !nullable {
+		typ += " NOT NULL"
+	}
+	t.column(name, typ, doc)
+}
+
+// Adds the definition of a column, numbering its name if it's taken.
+func (t *sqlTable) column(name, typ string, doc []string) {
+	unique := t.names.declare(name)
+
+	var column string
+	for _, line := range doc {
+		column += "  -- " + line + "\n"
+	}
+	t.columns = append(t.columns, column+"  "+sqlQuote(unique)+" "+typ)
+}
+
+// Returns a quoted SQL identifier.
+func sqlQuote(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
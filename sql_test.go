@@ -0,0 +1,102 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import "testing"
+
+func TestSQL(t *testing.T) {
+	testCases := []struct {
+		Dialect  string
+		Source   string
+		Expected string
+	}{
+		{"postgres", `{"a": true, "b": 1, "c": 1.5, "d": "foo", "e": null, "f": [1], "g": {"EUR-USD": 1.1}}`,
+			"CREATE TABLE \"root\" (\n" +
+				"  \"row_id\" BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,\n" +
+				"  \"a\" BOOLEAN NOT NULL,\n" +
+				"  \"b\" BIGINT NOT NULL,\n" +
+				"  \"c\" DOUBLE PRECISION NOT NULL,\n" +
+				"  \"d\" TEXT NOT NULL,\n" +
+				"  \"e\" JSONB,\n" +
+				"  \"f\" JSONB NOT NULL,\n" +
+				"  \"g\" JSONB NOT NULL\n" +
+				");\n"},
+		// Nested objects are flattened and fields which may be missing or
+		// null are nullable.
+		{"sqlite", `[{"user": {"name": "foo", "address": {"city": "bar"}}, "n": null}, {"n": 1}]`,
+			"CREATE TABLE \"root\" (\n" +
+				"  \"row_id\" INTEGER PRIMARY KEY,\n" +
+				"  \"n\" REAL,\n" +
+				"  \"user_address_city\" TEXT,\n" +
+				"  \"user_name\" TEXT\n" +
+				");\n"},
+		// Lists of objects are child tables.
+		{"postgres", `{"orderId": 1, "items": [{"sku": "a", "parts": [{"n": 1}]}, {"sku": "b", "parts": [{"n": 2}]}]}`,
+			"CREATE TABLE \"root\" (\n" +
+				"  \"row_id\" BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,\n" +
+				"  \"order_id\" BIGINT NOT NULL\n" +
+				");\n\n" +
+				"CREATE TABLE \"root_items\" (\n" +
+				"  \"row_id\" BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,\n" +
+				"  \"parent_row_id\" BIGINT NOT NULL REFERENCES \"root\" (\"row_id\"),\n" +
+				"  \"sku\" TEXT NOT NULL\n" +
+				");\n\n" +
+				"CREATE TABLE \"root_items_parts\" (\n" +
+				"  \"row_id\" BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,\n" +
+				"  \"parent_row_id\" BIGINT NOT NULL REFERENCES \"root_items\" (\"row_id\"),\n" +
+				"  \"n\" BIGINT NOT NULL\n" +
+				");\n"},
+		// Integers beyond int64 are numeric.
+		{"postgres", `{"a": 18446744073709551615, "b": 1}`,
+			"CREATE TABLE \"root\" (\n" +
+				"  \"row_id\" BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,\n" +
+				"  \"a\" NUMERIC NOT NULL,\n" +
+				"  \"b\" BIGINT NOT NULL\n" +
+				");\n"},
+		{"sqlite", `{"a": 18446744073709551615}`,
+			"CREATE TABLE \"root\" (\n" +
+				"  \"row_id\" INTEGER PRIMARY KEY,\n" +
+				"  \"a\" TEXT NOT NULL\n" +
+				");\n"},
+		{"sqlite", `[1, 2]`,
+			"CREATE TABLE \"root\" (\n" +
+				"  \"row_id\" INTEGER PRIMARY KEY,\n" +
+				"  \"value\" TEXT NOT NULL\n" +
+				");\n"},
+	}
+
+	for _, testCase := range testCases {
+		restore := withConfig(func(c *Config) {
+			c.lang = "sql"
+			c.sqlDialect = testCase.Dialect
+		})
+
+		tree, err := Parse(testCase.Source)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		formatted, _, err := sqlDDL{}.Emit(tree.Graph())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(formatted) != testCase.Expected {
+			t.Errorf("Source: %s Expected: %q Got: %q", testCase.Source, testCase.Expected, formatted)
+		}
+		restore()
+	}
+}
@@ -0,0 +1,348 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"math/big"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Matches strings encoding/json accepts as numbers in fields with the ,string
+// tag option.
+var numberLiteral = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// Observations of the values a tree was populated from. Stats are only
+// gathered when an option which uses them is enabled. The stats of a list
+// describe the lists themselves, Elements describes the values they contain.
+type Stats struct {
+	// Number of values observed.
+	Count int
+
+	// Distinct string values and the number of times each was observed.
+	// Values are discarded once more than valuesMax have been seen.
+	Values   map[string]int `json:",omitempty"`
+	Overflow bool           `json:",omitempty"`
+
+	// Range of integer values observed, and whether any number observed
+	// wasn't an integer.
+	Min        *big.Int `json:",omitempty"`
+	Max        *big.Int `json:",omitempty"`
+	Fractional bool     `json:",omitempty"`
+
+	// Number of string values observed and the number of those which hold
+	// an integer of any size, a number or a bool, and the range of the
+	// integers.
+	Strings      int      `json:",omitempty"`
+	IntStrings   int      `json:",omitempty"`
+	FloatStrings int      `json:",omitempty"`
+	BoolStrings  int      `json:",omitempty"`
+	MinIntString *big.Int `json:",omitempty"`
+	MaxIntString *big.Int `json:",omitempty"`
+
+	// Number of strings which are base64 encoded and at least
+	// config.base64Min long.
+	Base64Strings int `json:",omitempty"`
+
+	// Number of strings each enabled detector matched.
+	Detected map[string]int `json:",omitempty"`
+
+	// Number of values of each JSON kind observed, e.g.: null or string.
+	// Kinds are only recorded for emitters other than Go's and nullable
+	// annotations.
+	Kinds map[string]int `json:",omitempty"`
+
+	// First value observed, as JSON, if examples are annotated.
+	Example string `json:",omitempty"`
+
+	// Range of lengths of strings, in runes, and of lists, and range of
+	// numbers observed, if constraints are declared.
+	MinLength *int     `json:",omitempty"`
+	MaxLength *int     `json:",omitempty"`
+	MinFloat  *float64 `json:",omitempty"`
+	MaxFloat  *float64 `json:",omitempty"`
+
+	Elements *Stats `json:",omitempty"`
+}
+
+// Returns true if any enabled option requires stats.
+func collecting() bool {
+	return config.enum || config.narrow || config.big == "number" || config.big == "bigint" ||
+		config.quoted || len(config.quotedPaths) > 0 || config.base64 || len(config.detectors) > 0 ||
+		config.unions || config.command == "sample" || config.command == "validate" || emitterStats() || len(config.doc) > 0 ||
+		config.decode || config.validation != ""
+}
+
+// Returns stats for a single observed value, nil unless collecting.
+func newStats() *Stats {
+	if !collecting() {
+		return nil
+	}
+	return &Stats{Count: 1}
+}
+
+// Records a string value.
+func (s *Stats) observeString(v string) {
+	if s == nil {
+		return
+	}
+
+	s.Strings++
+	if numberLiteral.MatchString(v) {
+		s.FloatStrings++
+		if i, ok := integer(json.Number(v)); ok {
+			s.IntStrings++
+			if s.MinIntString == nil || i.Cmp(s.MinIntString) < 0 {
+				s.MinIntString = i
+			}
+			if s.MaxIntString == nil || i.Cmp(s.MaxIntString) > 0 {
+				s.MaxIntString = i
+			}
+		}
+	}
+	if v == "true" || v == "false" {
+		s.BoolStrings++
+	}
+	if config.base64 && isBase64(v) {
+		s.Base64Strings++
+	}
+	s.detect(v)
+	s.observeLength(utf8.RuneCountInString(v))
+
+	if s.Overflow || !config.enum && !config.unions {
+		return
+	}
+
+	if s.Values == nil {
+		s.Values = make(map[string]int)
+	}
+	s.Values[v]++
+	s.limit()
+}
+
+// Records the JSON kind of a value.
+func (s *Stats) observeKind(kind string) {
+	if s == nil || !emitterStats() && !config.doc["nullable"] && config.validation == "" {
+		return
+	}
+
+	if s.Kinds == nil {
+		s.Kinds = make(map[string]int)
+	}
+	s.Kinds[kind]++
+}
+
+// Records the length of a string or a list.
+func (s *Stats) observeLength(n int) {
+	if s == nil || config.validation == "" {
+		return
+	}
+
+	if s.MinLength == nil || n < *s.MinLength {
+		s.MinLength = &n
+	}
+	if s.MaxLength == nil || n > *s.MaxLength {
+		s.MaxLength = &n
+	}
+}
+
+// Records a numeric value.
+func (s *Stats) observeNumber(n json.Number) {
+	if s == nil {
+		return
+	}
+
+	if f, err := n.Float64(); err == nil && config.validation != "" {
+		if s.MinFloat == nil || f < *s.MinFloat {
+			s.MinFloat = &f
+		}
+		if s.MaxFloat == nil || f > *s.MaxFloat {
+			s.MaxFloat = &f
+		}
+	}
+
+	i, ok := integer(n)
+	if !ok {
+		s.Fractional = true
+		return
+	}
+
+	if s.Min == nil || i.Cmp(s.Min) < 0 {
+		s.Min = i
+	}
+	if s.Max == nil || i.Cmp(s.Max) > 0 {
+		s.Max = i
+	}
+}
+
+// Parses a number written as an integer of any size.
+func integer(n json.Number) (*big.Int, bool) {
+	if strings.ContainsAny(string(n), ".eE") {
+		return nil, false
+	}
+	return new(big.Int).SetString(string(n), 10)
+}
+
+// Returns the most distinct string values any enabled option uses.
+func valuesMax() (max int) {
+	if config.enum {
+		max = config.enumMax
+	}
+	if config.unions && config.unionMax > max {
+		max = config.unionMax
+	}
+	return
+}
+
+// Discards distinct values once there are too many to be useful.
+func (s *Stats) limit() {
+	if len(s.Values) > valuesMax() {
+		s.Values = nil
+		s.Overflow = true
+	}
+}
+
+// Returns the stats of the values of a tree, looking through lists.
+func (t *Tree) values() *Stats {
+	s := t.Stats
+	for s != nil && s.Elements != nil {
+		s = s.Elements
+	}
+	return s
+}
+
+// Combines the observations of two sets of stats, either may be nil. The
+// result may be either of the arguments, which may be modified.
+func mergeStats(a, b *Stats) *Stats {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+
+	a.Count += b.Count
+
+	a.Overflow = a.Overflow || b.Overflow
+	if a.Overflow {
+		a.Values = nil
+	} else if b.Values != nil {
+		if a.Values == nil {
+			a.Values = make(map[string]int)
+		}
+		for v, n := range b.Values {
+			a.Values[v] += n
+		}
+		a.limit()
+	}
+
+	if b.Min != nil && (a.Min == nil || b.Min.Cmp(a.Min) < 0) {
+		a.Min = b.Min
+	}
+	if b.Max != nil && (a.Max == nil || b.Max.Cmp(a.Max) > 0) {
+		a.Max = b.Max
+	}
+	a.Fractional = a.Fractional || b.Fractional
+
+	a.Strings += b.Strings
+	a.IntStrings += b.IntStrings
+	if b.MinIntString != nil && (a.MinIntString == nil || b.MinIntString.Cmp(a.MinIntString) < 0) {
+		a.MinIntString = b.MinIntString
+	}
+	if b.MaxIntString != nil && (a.MaxIntString == nil || b.MaxIntString.Cmp(a.MaxIntString) > 0) {
+		a.MaxIntString = b.MaxIntString
+	}
+	a.FloatStrings += b.FloatStrings
+	a.BoolStrings += b.BoolStrings
+	a.Base64Strings += b.Base64Strings
+	for name, n := range b.Detected {
+		if a.Detected == nil {
+			a.Detected = make(map[string]int)
+		}
+		a.Detected[name] += n
+	}
+
+	for kind, n := range b.Kinds {
+		if a.Kinds == nil {
+			a.Kinds = make(map[string]int)
+		}
+		a.Kinds[kind] += n
+	}
+
+	if a.Example == "" {
+		a.Example = b.Example
+	}
+
+	if b.MinLength != nil && (a.MinLength == nil || *b.MinLength < *a.MinLength) {
+		a.MinLength = b.MinLength
+	}
+	if b.MaxLength != nil && (a.MaxLength == nil || *b.MaxLength > *a.MaxLength) {
+		a.MaxLength = b.MaxLength
+	}
+	if b.MinFloat != nil && (a.MinFloat == nil || *b.MinFloat < *a.MinFloat) {
+		a.MinFloat = b.MinFloat
+	}
+	if b.MaxFloat != nil && (a.MaxFloat == nil || *b.MaxFloat > *a.MaxFloat) {
+		a.MaxFloat = b.MaxFloat
+	}
+
+	a.Elements = mergeStats(a.Elements, b.Elements)
+
+	return a
+}
+
+// Merges the stats of two trees with identical structure.
+func mergeTreeStats(a, b *Tree) {
+	a.Stats = mergeStats(a.Stats, b.Stats)
+	for idx := range a.Children {
+		mergeTreeStats(a.Children[idx], b.Children[idx])
+	}
+}
+
+// Returns a deep copy of stats, which may be nil.
+func (s *Stats) copy() *Stats {
+	if s == nil {
+		return nil
+	}
+
+	c := *s
+	c.Values = nil
+	for v, n := range s.Values {
+		if c.Values == nil {
+			c.Values = make(map[string]int)
+		}
+		c.Values[v] = n
+	}
+	c.Detected = nil
+	for name, n := range s.Detected {
+		if c.Detected == nil {
+			c.Detected = make(map[string]int)
+		}
+		c.Detected[name] = n
+	}
+	c.Kinds = nil
+	for kind, n := range s.Kinds {
+		if c.Kinds == nil {
+			c.Kinds = make(map[string]int)
+		}
+		c.Kinds[kind] = n
+	}
+	c.Elements = s.Elements.copy()
+	return &c
+}
@@ -0,0 +1,114 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Populates the tree from the next value of the decoder one token at a time.
+// Lists are normalized as their elements are read, so only the squashed type
+// of each list is kept rather than every element. Memory use is bounded by
+// the size of the resulting tree instead of the size of the input. The
+// resulting tree is identical to one produced by Populate and Normalize.
+func (t *Tree) Infer(dec *json.Decoder) error {
+	// Numbers must be parsed as json.Number to distinguish ints from floats.
+	dec.UseNumber()
+	if err := t.infer(dec, newSigner()); err != nil {
+		return err
+	}
+
+	t.ResolveTuples()
+	return nil
+}
+
+func (t *Tree) infer(dec *json.Decoder, s *signer) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	delim, ok := token.(json.Delim)
+	if !ok {
+		// Primitive values are handled the same way as when populating.
+		t.Populate(token)
+		return nil
+	}
+
+	t.Stats = newStats()
+	if delim == '[' {
+		t.Stats.observeKind("array")
+	} else {
+		t.Stats.observeKind("object")
+	}
+
+	switch delim {
+	case '[':
+		// Set list to true and merge each element into the list's type as
+		// it is read.
+		t.List = true
+		e := newElements(s, config.tuples)
+		length := 0
+		for dec.More() {
+			element := &Tree{}
+			if err := element.infer(dec, s); err != nil {
+				return err
+			}
+			e.add(element)
+			length++
+		}
+		t.Stats.observeLength(length)
+		e.finish(t)
+	case '{':
+		// Set type to struct and recurse for each child. Store key as child
+		// name, later duplicate keys replace earlier ones as when decoding
+		// into a map.
+		t.Type = Struct
+		fields := make(map[string]*Tree)
+		for dec.More() {
+			token, err := dec.Token()
+			if err != nil {
+				return err
+			}
+
+			key, ok := token.(string)
+			if !ok {
+				return fmt.Errorf("expected object key, got %v", token)
+			}
+
+			child := &Tree{Name: Ident(key)}
+			if err := child.infer(dec, s); err != nil {
+				return err
+			}
+			fields[key] = child
+		}
+
+		for _, child := range fields {
+			t.Children = append(t.Children, child)
+		}
+		// Sort children for consistent output.
+		sort.Sort(t)
+	default:
+		return fmt.Errorf("unexpected delimiter %v", delim)
+	}
+
+	// Consume the closing delimiter.
+	_, err = dec.Token()
+	return err
+}
@@ -0,0 +1,88 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+// Inferring while streaming must produce the same tree as populating and
+// normalizing.
+func TestInfer(t *testing.T) {
+	testJSON, err := ioutil.ReadFile("test.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sources := []string{
+		`null`, `true`, `1`, `1.0`, `"foo"`,
+		`[]`, `[null]`, `[true, false]`, `[1, 2.5]`, `[true, "foo"]`, `[true, 1, "foo"]`,
+		`[[1], [2]]`, `[[{"a": 1}], [{"b": 2}]]`, `[{"a": 1}, 2]`,
+		`{}`, `{"a": {"b": [1, 2]}}`, `{"a": 1, "a": "foo"}`,
+		`[{"a": {"b": 1}}, {"a": {"b": "foo"}}, {"a": {"b": 1}}]`,
+		`[{"a": [{"b": 1}]}, {"a": [{"c": 1}]}]`,
+		string(testJSON),
+	}
+
+	for _, source := range sources {
+		expected, err := Parse(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var tree Tree
+		if err := tree.Infer(json.NewDecoder(bytes.NewBufferString(source))); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(tree, expected) {
+			t.Errorf("Source: %s Expected: %+v Got: %+v", source, expected, tree)
+		}
+	}
+}
+
+func TestInferError(t *testing.T) {
+	sources := []string{``, `[1, 2`, `{"a": }`, `{"a": 1]`}
+
+	for _, source := range sources {
+		var tree Tree
+		if err := tree.Infer(json.NewDecoder(bytes.NewBufferString(source))); err == nil {
+			t.Errorf("Source: %q Expected error.", source)
+		}
+	}
+}
+
+func BenchmarkInfer100k(b *testing.B) {
+	data, err := json.Marshal(benchmarkList(100000))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var tree Tree
+		if err := tree.Infer(json.NewDecoder(bytes.NewReader(data))); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"embed"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Width and number of the lines of declarations.
+const (
+	lineWidth = 1 << 6
+	lineCount = 1 << 20
+)
+
+// Assigns positions to the syntax of a declaration as it is built. The
+// printer lays out declarations and places comments by their positions, so
+// each line is given the next line of a file and each token on it the next
+// column. Declarations are printed separately so they share the file.
+type lines struct {
+	file   *token.File
+	n, col int
+
+	// Comments attached to the declaration.
+	comments []*ast.CommentGroup
+
+	// Declaration being built when this one was started.
+	outer *lines
+}
+
+// Returns the position of the start of a new line.
+func (l *lines) line() token.Pos {
+	l.n++
+	for l.file.LineCount() < l.n {
+		l.file.AddLine(l.file.LineCount() * lineWidth)
+	}
+	l.col = 0
+	return l.file.LineStart(l.n)
+}
+
+// Returns the position of the next token on the current line.
+func (l *lines) pos() token.Pos {
+	l.col++
+	return l.file.LineStart(l.n) + token.Pos(l.col)
+}
+
+// Starts a declaration, declarations started while it is built are nested.
+func (f *formatter) begin() *lines {
+	if f.file == nil {
+		f.file = f.fset.AddFile("", -1, lineWidth*lineCount)
+	}
+
+	f.lines = &lines{file: f.file, outer: f.lines}
+	return f.lines
+}
+
+// Finishes a declaration, returning to the one it is nested in.
+func (f *formatter) end(l *lines) {
+	f.lines = l.outer
+}
+
+// Returns the source of a declaration.
+func (f *formatter) print(node ast.Node, l *lines) string {
+	var buf bytes.Buffer
+	cfg := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+	if err := cfg.Fprint(&buf, f.fset, &printer.CommentedNode{Node: node, Comments: l.comments}); err != nil {
+		f.fail(err)
+	}
+	return buf.String() + "\n"
+}
+
+//go:embed templates/*.tmpl
+var templateFiles embed.FS
+
+// Templates of the functions and methods declared as source, by file name.
+// Keys and values are only written into source by the templates' functions,
+// so they're escaped the same way everywhere: quote writes a string literal,
+// format a format string of text followed by verbs and tag the tag of a key.
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"quote":  strconv.Quote,
+	"format": func(text, verbs string) string { return strconv.Quote(formatText(text) + verbs) },
+	"tag":    func(key string) string { return Ident(key).Tag() },
+	"join":   strings.Join,
+}).ParseFS(templateFiles, "templates/*.tmpl"))
+
+// Returns text as a format string, which formats as the text.
+func formatText(text string) string {
+	return strings.Replace(text, "%", "%%", -1)
+}
+
+// Returns declarations executed from a template and parsed as parse does.
+// An error is recorded if the template can't be executed.
+func (f *formatter) execute(name string, data interface{}) string {
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, name, data); err != nil {
+		f.fail(err)
+		return ""
+	}
+	return f.parse(buf.String())
+}
+
+// Returns declarations written as source, parsed and printed so they're
+// formatted. Parsing only checks their syntax, not that they type check. The
+// source is returned as it is, and an error recorded, if it can't be parsed.
+func (f *formatter) parse(src string) string {
+	return f.parseRenamed(src, nil)
+}
+
+// Returns declarations written as source with identifiers renamed, other
+// than the names of fields and methods selected.
+func (f *formatter) parseRenamed(src string, names map[string]string) string {
+	const clause = "package p\n\n"
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", clause+src, parser.ParseComments)
+	if err != nil {
+		f.fail(err)
+		return src
+	}
+
+	ast.Inspect(file, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.SelectorExpr:
+			ast.Inspect(node.X, func(node ast.Node) bool {
+				if ident, ok := node.(*ast.Ident); ok && names[ident.Name] != "" {
+					ident.Name = names[ident.Name]
+				}
+				return true
+			})
+			return false
+		case *ast.Ident:
+			if names[node.Name] != "" {
+				node.Name = names[node.Name]
+			}
+		}
+		return true
+	})
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, file); err != nil {
+		f.fail(err)
+		return src
+	}
+	return strings.TrimPrefix(buf.String(), clause)
+}
+
+// Records the first error building declarations.
+func (f *formatter) fail(err error) {
+	if f.err == nil {
+		f.err = err
+	}
+}
+
+// Returns the syntax of a type written as source: a named type, possibly
+// qualified by its package, or a pointer, slice, array or map of one.
+// Types which aren't understood are written as they are.
+func (l *lines) parseType(typ string) ast.Expr {
+	switch {
+	case strings.HasPrefix(typ, "*"):
+		return &ast.StarExpr{X: l.parseType(typ[1:])}
+	case strings.HasPrefix(typ, "[]"):
+		return &ast.ArrayType{Elt: l.parseType(typ[2:])}
+	case typ == Interface.String():
+		// Braces on the same line are written together.
+		pos := l.pos()
+		return &ast.InterfaceType{Methods: &ast.FieldList{Opening: pos, Closing: pos}}
+	case strings.HasPrefix(typ, "["), strings.HasPrefix(typ, "map["):
+		// The length of arrays, or key type of maps, is up to the matching
+		// bracket.
+		open := strings.Index(typ, "[")
+		depth := 0
+		for idx := open; idx < len(typ); idx++ {
+			switch typ[idx] {
+			case '[':
+				depth++
+			case ']':
+				depth--
+			}
+			if depth > 0 {
+				continue
+			}
+
+			if open > 0 {
+				return &ast.MapType{Key: l.parseType(typ[open+1 : idx]), Value: l.parseType(typ[idx+1:])}
+			}
+			return &ast.ArrayType{Len: &ast.BasicLit{Kind: token.INT, Value: typ[1:idx]}, Elt: l.parseType(typ[idx+1:])}
+		}
+	case strings.Count(typ, ".") == 1:
+		dot := strings.Index(typ, ".")
+		return &ast.SelectorExpr{X: ast.NewIdent(typ[:dot]), Sel: ast.NewIdent(typ[dot+1:])}
+	}
+	return ast.NewIdent(typ)
+}
+
+// Returns the source of a field of a struct named name, on lines of its own.
+func (f *formatter) fieldSource(t, parent *Tree, name string) string {
+	l := f.begin()
+	defer f.end(l)
+
+	// The field is formatted as the only field of a struct with the stats
+	// of its parent.
+	l.line()
+	st := f.structType(&Tree{Type: Struct, Children: []*Tree{t}, Stats: parent.Stats})
+
+	// Fields renamed to keep them unique are tagged with their key.
+	field := st.Fields.List[0]
+	if field.Names[0].Name != name {
+		field.Names[0].Name = name
+		if field.Tag == nil {
+			field.Tag = &ast.BasicLit{ValuePos: field.Type.End(), Kind: token.STRING, Value: t.Name.Tag()}
+		}
+	}
+	src := f.print(st, l)
+
+	// Only the lines between the braces are the field.
+	return src[strings.Index(src, "\n")+1 : strings.LastIndex(src, "}")]
+}
@@ -0,0 +1,205 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"go/printer"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestIdentTag(t *testing.T) {
+	testCases := []struct {
+		Ident    Ident
+		Options  []string
+		Expected string
+	}{
+		{"foo", nil, "`json:\"foo\"`"},
+		{"foo", []string{"string"}, "`json:\"foo,string\"`"},
+		{`a"b`, nil, "`json:\"a\\\"b\"`"},
+		{"a`b", nil, "\"json:\\\"a`b\\\"\""},
+		{`a\b`, nil, "`json:\"a\\\\b\"`"},
+	}
+
+	for _, testCase := range testCases {
+		if tag := testCase.Ident.Tag(testCase.Options...); tag != testCase.Expected {
+			t.Errorf("Ident: %s Expected: %s Got: %s", testCase.Ident, testCase.Expected, tag)
+		}
+	}
+}
+
+// Keys which can't be written in a raw string are still valid tags.
+func TestFormatTags(t *testing.T) {
+	tree, err := Parse("{\"a\\\"b\": 1, \"c`d\": true}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	formatted, err := tree.Format()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "type _ struct {\n" +
+		"\tAb int64 `json:\"a\\\"b\"`\n" +
+		"\tCd bool  \"json:\\\"c`d\\\"\"\n" +
+		"}\n"
+	if string(formatted) != expected {
+		t.Errorf("Expected: %q Got: %q", expected, formatted)
+	}
+}
+
+func TestParseType(t *testing.T) {
+	testCases := []string{
+		"int64",
+		"interface{}",
+		"*big.Int",
+		"[]byte",
+		"[16]byte",
+		"map[string][]*netip.Addr",
+		"[][2]json.RawMessage",
+	}
+
+	f := newFormatter()
+	l := f.begin()
+	l.line()
+	for _, typ := range testCases {
+		var buf bytes.Buffer
+		if err := printer.Fprint(&buf, token.NewFileSet(), l.parseType(typ)); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != typ {
+			t.Errorf("Expected: %q Got: %q", typ, buf.String())
+		}
+	}
+}
+
+// Declarations written as source which can't be parsed are reported.
+func TestFormatInvalidDeclaration(t *testing.T) {
+	saved := detectors
+	defer func() { detectors = saved }()
+	defer withConfig(func(c *Config) {
+		c.detectors = map[string]bool{"invalid": true}
+	})()
+
+	RegisterDetector(&Detector{
+		Name:        "invalid",
+		Description: "invalid",
+		Match:       func(string) bool { return true },
+		Type:        "Invalid",
+		Declaration: "type Invalid struct {",
+	})
+
+	tree, err := Parse(`{"a": "foo"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tree.Format(); err == nil {
+		t.Errorf("Expected error formatting invalid declaration")
+	}
+}
+
+// Vets generated source in a module of its own, skipping the
+// test if the go command isn't available.
+func vet(t *testing.T, src []byte) {
+	goCmd, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{"go.mod": "module vet\n\ngo 1.16\n", "types.go": "package main\n\n" + string(src), "main.go": "package main\n\nfunc main() {}\n"}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(goCmd, "vet", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GO111MODULE=on", "GOFLAGS=")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("Expected: go vet to pass Got: %s\n%s", out, src)
+	}
+}
+
+// Functions and methods written as source must type check, not only parse.
+func TestFormatVet(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.tuples = true
+		c.tupleMax = 8
+		c.unions = true
+		c.unionMax = 16
+		c.enum = true
+		c.enumMax = 10
+		c.enumSamples = 1
+		c.enumStrict = true
+		c.decode = true
+		c.recursive = true
+	})()
+
+	tree, err := Parse(`{"pair": ["x", 1], "status": "a", "events": [{"type": "a", "n": 1}, {"type": "b", "s": "x"}], "node": {"name": "a", "node": {"name": "b"}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree.DetectRecursion()
+	tree.Name = "Order"
+
+	formatted, err := tree.Format()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vet(t, formatted)
+}
+
+// Keys and values holding quotes, backslashes and verbs are escaped by the
+// templates' functions, so the source they're written into still vets.
+func TestTemplateEscaping(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.unions = true
+		c.unionMax = 16
+		c.unionKey = `t"y%pe`
+		c.enum = true
+		c.enumMax = 10
+		c.enumSamples = 1
+		c.enumStrict = true
+		c.decode = true
+		c.validation = "method"
+	})()
+
+	tree, err := Parse(`{"s%d": ["a\"b", "%d", "c\\d"], "events": [{"t\"y%pe": "a%s", "n": 1}, {"t\"y%pe": "b\"", "m": "x"}], "l%v": [{"k%q": 1}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree.Name = "Order"
+
+	formatted, err := tree.Format()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{`"unknown Events t\"y%%pe %q"`, `"a\"b"`, `fmt.Sprintf("l%%v[%d].k%%q: required", i0)`} {
+		if !bytes.Contains(formatted, []byte(expected)) {
+			t.Errorf("Expected: %s Got: %s", expected, formatted)
+		}
+	}
+	vet(t, formatted)
+}
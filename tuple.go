@@ -0,0 +1,158 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import "strconv"
+
+// Returns true if two tuples have the same number of positions and each
+// position of one can be merged with the same position of the other.
+func (e *elements) compatible(a, b *Tree) bool {
+	if a.List != b.List || len(a.Children) != len(b.Children) {
+		return false
+	}
+
+	for idx, position := range a.Children {
+		other := b.Children[idx]
+		switch {
+		case e.signer.sign(position) == e.signer.sign(other):
+		case widens(position, other):
+		case position.Type == Tuple && other.Type == Tuple && e.compatible(position, other):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Returns true if one of two numbers is an int and the other a float, so
+// both are floats.
+func widens(a, b *Tree) bool {
+	return !a.List && !b.List && a.Type != b.Type &&
+		(a.Type == Int || a.Type == Float) && (b.Type == Int || b.Type == Float)
+}
+
+// Merges a compatible tuple into another position by position.
+func (e *elements) mergeTuple(a, b *Tree) {
+	for idx, position := range a.Children {
+		other := b.Children[idx]
+		switch {
+		case e.signer.sign(position) == e.signer.sign(other):
+			mergeTreeStats(position, other)
+		case widens(position, other):
+			position.Type = Float
+			position.Stats = mergeStats(position.Stats, other.Stats)
+		default:
+			e.mergeTuple(position, other)
+		}
+		e.signer.forget(position)
+	}
+
+	a.Stats = mergeStats(a.Stats, b.Stats)
+	e.signer.forget(a)
+	e.signer.forgetTree(b)
+}
+
+// Turns a tuple into a list of its positions. Lists of tuples would be lists
+// of lists, which can't be declared, so their elements are unknown.
+func (e *elements) demote(t *Tree) {
+	if t.List {
+		e.signer.forgetTree(t)
+		t.Type = Interface
+		t.Children = nil
+		return
+	}
+
+	list := newElements(e.signer, false)
+	for _, position := range t.Children {
+		e.signer.forget(position)
+		position.Name = ""
+		list.add(position)
+	}
+
+	e.signer.forget(t)
+	t.List = true
+	list.finish(t)
+}
+
+// Returns true if a tuple should be declared as a struct: its positions
+// don't all have the same type and none of them are null. Ints and floats
+// are the same type, as a list of both is a list of floats.
+func (t *Tree) tuple() bool {
+	if t.Type != Tuple {
+		return false
+	}
+
+	first := t.Children[0]
+	heterogeneous := false
+	for _, position := range t.Children {
+		if position.Type == Interface {
+			return false
+		}
+		heterogeneous = heterogeneous || position.Type != first.Type && !widens(position, first) || position.List != first.List
+	}
+	return heterogeneous
+}
+
+// Replaces tuples whose positions all have the same type with lists of them,
+// so only tuples declared as structs and lists of tuples remain. Tuples are
+// only resolved once the tree is complete, as merging tuples may widen their
+// positions.
+func (t *Tree) ResolveTuples() {
+	for t.Type == Tuple && !t.List && !t.tuple() {
+		newElements(newSigner(), false).demote(t)
+	}
+
+	for _, child := range t.Children {
+		child.ResolveTuples()
+	}
+}
+
+// Declares a struct named after the field with a field for each position of
+// a tuple, and methods decoding and encoding it as an array.
+func (f *formatter) tuple(t *Tree) string {
+	name := t.Name.String()
+	if name == "_" {
+		name = "Tuple"
+	}
+	name = f.names.declare(name)
+
+	s := &Tree{Name: Ident(name), Type: Struct}
+	var fields []string
+	for idx, position := range t.Children {
+		field := *position
+		field.Name = Ident("Field" + strconv.Itoa(idx))
+		f.positions[&field] = true
+		s.Children = append(s.Children, &field)
+		fields = append(fields, string(field.Name))
+	}
+
+	// The struct is declared at the top level, outside of the field being
+	// formatted.
+	decl := f.structDecl(name, s)
+
+	f.imports["encoding/json"] = true
+	f.imports["fmt"] = true
+
+	methods := f.execute("tuple.tmpl", struct {
+		Name   string
+		Fields []string
+	}{name, fields})
+
+	f.decls = append(f.decls, decl+"\n"+methods)
+
+	return name
+}
@@ -0,0 +1,222 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTupleFields(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.tuples = true
+		c.tupleMax = 4
+	})()
+
+	testCases := []struct {
+		Source   string
+		Expected string
+	}{
+		// Positions with different types are tuples.
+		{`{"a": ["EUR", 12.5, true]}`, "\tA A `json:\"a\"`\n"},
+		// Lists of tuples are squashed, ints are widened to floats.
+		{`{"a": [["EUR", 12.5], ["USD", 3]]}`, "\tA []A `json:\"a\"`\n"},
+		{`{"a": [[1609459200, 12.5], [1609459260, 13]]}`, "\tA []A `json:\"a\"`\n"},
+		// Positions of the same type are lists, unless they're positions of
+		// a list of tuples, e.g. GeoJSON coordinates.
+		{`{"a": ["foo", "bar"]}`, "\tA []string `json:\"a\"`\n"},
+		{`{"a": [10, 2.5, 3]}`, "\tA []float64 `json:\"a\"`\n"},
+		{`{"a": [[102.0, 0.5], [103.0, 1.0]]}`, "\tA []A `json:\"a\"`\n"},
+		{`{"a": [[102.0, 0.5], [103.0, 1.0], [104.0, 0.0], [105.0, 1.0], [102.0, 0.5]]}`, "\tA []A `json:\"a\"`\n"},
+		{`{"a": [{"b": 1}, {"c": 2}]}`, "\tA []struct {\n\t\tB int64 `json:\"b\"`\n\t\tC int64 `json:\"c\"`\n\t} `json:\"a\"`\n"},
+		// Null positions, differing lengths, conflicting positions and long
+		// lists aren't tuples.
+		{`{"a": [1, null]}`, "\tA []interface{} `json:\"a\"`\n"},
+		{`{"a": [["EUR", 12.5], ["USD", 3, true]]}`, "\tA []interface{} `json:\"a\"`\n"},
+		{`{"a": [["EUR", 12.5], [true, 3]]}`, "\tA []interface{} `json:\"a\"`\n"},
+		{`{"a": ["EUR", 1, "USD", 2, "GBP"]}`, "\tA []interface{} `json:\"a\"`\n"},
+	}
+
+	for _, testCase := range testCases {
+		tree, err := Parse(testCase.Source)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		formatted, err := tree.Format()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "type _ struct {\n" + testCase.Expected + "}\n"
+		if !strings.Contains(string(formatted), expected) {
+			t.Errorf("Source: %s Expected: %q Got: %q", testCase.Source, expected, formatted)
+		}
+	}
+}
+
+// Tuples of a single type are lists once the tree is complete.
+func TestResolveTuples(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.tuples = true
+		c.tupleMax = 4
+	})()
+
+	tree, err := Parse(`{"a": ["foo", "bar"], "b": [[1, 2], [3, 4]], "c": ["EUR", 12.5]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		List bool
+		Type Type
+	}{{true, String}, {true, Tuple}, {false, Tuple}}
+	for idx, child := range tree.Children {
+		if child.List != expected[idx].List || child.Type != expected[idx].Type {
+			t.Errorf("Field: %s Expected: %s list %t Got: %s list %t", child.Name, expected[idx].Type, expected[idx].List, child.Type, child.List)
+		}
+	}
+
+	// The positions of the list of tuples are the numbers themselves.
+	for _, position := range tree.Children[1].Children {
+		if position.List || position.Type != Int {
+			t.Errorf("Expected: int64 Got: %s list %t", position.Type, position.List)
+		}
+	}
+}
+
+func TestTupleFormat(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.tuples = true
+		c.tupleMax = 8
+	})()
+
+	tree, err := Parse(`[{"rate": ["EUR", 12.5, true]}, {"rate": ["USD", 3, false]}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	formatted, err := tree.Format()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "import (\n" +
+		"\t\"encoding/json\"\n" +
+		"\t\"fmt\"\n" +
+		")\n" +
+		"\n" +
+		"type _ []struct {\n" +
+		"\tRate Rate `json:\"rate\"`\n" +
+		"}\n" +
+		"\n" +
+		"type Rate struct {\n" +
+		"\tField0 string\n" +
+		"\tField1 float64\n" +
+		"\tField2 bool\n" +
+		"}\n" +
+		"\n" +
+		"func (v *Rate) UnmarshalJSON(data []byte) error {\n" +
+		"\tvar elements []json.RawMessage\n" +
+		"\tif err := json.Unmarshal(data, &elements); err != nil {\n" +
+		"\t\treturn err\n" +
+		"\t}\n" +
+		"\n" +
+		"\tfields := []interface{}{&v.Field0, &v.Field1, &v.Field2}\n" +
+		"\tif len(elements) != len(fields) {\n" +
+		"\t\treturn fmt.Errorf(\"Rate: expected %d elements, got %d\", len(fields), len(elements))\n" +
+		"\t}\n" +
+		"\n" +
+		"\tfor idx, element := range elements {\n" +
+		"\t\tif err := json.Unmarshal(element, fields[idx]); err != nil {\n" +
+		"\t\t\treturn err\n" +
+		"\t\t}\n" +
+		"\t}\n" +
+		"\n" +
+		"\treturn nil\n" +
+		"}\n" +
+		"\n" +
+		"func (v Rate) MarshalJSON() ([]byte, error) {\n" +
+		"\treturn json.Marshal([]interface{}{v.Field0, v.Field1, v.Field2})\n" +
+		"}\n"
+
+	if string(formatted) != expected {
+		t.Errorf("Expected: %q Got: %q", expected, formatted)
+	}
+}
+
+// Tuples must be inferred the same way while streaming.
+func TestTupleInfer(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.tuples = true
+		c.tupleMax = 4
+	})()
+
+	sources := []string{
+		`["EUR", 12.5, true]`,
+		`[["EUR", 12.5], ["USD", 3]]`,
+		`[["EUR", 12.5], ["USD", 3, true]]`,
+		`[{"a": [1, "foo"]}, {"a": [2.5, "bar"]}, {"a": [3]}]`,
+		`[[["a", 1], ["b", 2]], [["c", 3], ["d", 4]]]`,
+		`[1, "foo", 2, "bar", 3]`,
+	}
+
+	for _, source := range sources {
+		expected, err := Parse(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var tree Tree
+		if err := tree.Infer(json.NewDecoder(bytes.NewBufferString(source))); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(tree, expected) {
+			t.Errorf("Source: %s Expected: %+v Got: %+v", source, expected, tree)
+		}
+	}
+}
+
+func TestTupleValidate(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.tuples = true
+		c.tupleMax = 8
+	})()
+
+	testCases := []ValidateTestCase{
+		{`["EUR", 12.5, true]`, `["USD", 3, false]`, nil},
+		{`["EUR", 12.5, true]`, `["USD", "3", false, 1]`, []ValidationError{
+			{".", TypeMismatch, "expected 3 elements, got 4"},
+		}},
+		{`["EUR", 12.5, true]`, `["USD", "3", false]`, []ValidationError{
+			{".[1]", TypeMismatch, "expected float64, got string"},
+		}},
+		{`["EUR", 12.5, true]`, `{}`, []ValidationError{
+			{".", TypeMismatch, "expected tuple, got object"},
+		}},
+		{`["foo", "bar"]`, `["foo", "bar", "baz"]`, nil},
+		{`{"coordinates": [[102.0, 0.5], [103.0, 1.0]]}`, `{"coordinates": [[102.0, 0.5], [103.0, 1.0], [104.0, 0.0]]}`, nil},
+	}
+
+	for _, testCase := range testCases {
+		testCase.TestValidate(t)
+	}
+}
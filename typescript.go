@@ -0,0 +1,139 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Matches keys which needn't be quoted in TypeScript.
+var tsIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// TypeScript types of JSON kinds.
+var tsKinds = map[string]string{
+	"bool":   "boolean",
+	"number": "number",
+	"string": "string",
+	"object": "Record<string, unknown>",
+	"array":  "unknown[]",
+	"null":   "null",
+}
+
+func init() {
+	RegisterEmitter("typescript", typeScript{})
+}
+
+// Emits TypeScript declarations: an interface for each struct, union types
+// for unions and for values of different kinds, and tuple types for tuples.
+// Optional fields are marked optional and nullable types are unions with
+// null.
+type typeScript struct{}
+
+func (typeScript) Emit(g *Graph) ([]byte, []string, error) {
+	var decls []string
+
+	// Types other than a struct are aliased.
+	if r := g.Root; r.Decl == nil || r.Decl.Name != g.Name || r.List || r.Nullable {
+		decls = append(decls, "export type "+g.Name+" = "+tsType(r)+";\n")
+	}
+
+	for _, d := range g.Types {
+		switch d.Type {
+		case Struct:
+			decl := "export interface " + d.Name + " {\n"
+			for _, field := range d.Fields {
+				key := field.Key
+				if !tsIdentifier.MatchString(key) {
+					key = strconv.Quote(key)
+				}
+
+				// Variants hold the value of the discriminator.
+				if d.Value != "" && field.Key == d.Discriminator {
+					decl += "  " + key + ": " + strconv.Quote(d.Value) + ";\n"
+					continue
+				}
+
+				if field.Optional {
+					key += "?"
+				}
+				if len(field.Doc) > 0 {
+					decl += "  /** " + strings.Join(field.Doc, " ") + " */\n"
+				}
+				decl += "  " + key + ": " + tsType(field.Type) + ";\n"
+			}
+			decls = append(decls, decl+"}\n")
+		case Union:
+			var variants []string
+			for _, variant := range d.Variants {
+				variants = append(variants, variant.Name)
+			}
+			decls = append(decls, "export type "+d.Name+" = "+strings.Join(variants, " | ")+";\n")
+		}
+	}
+
+	return []byte(strings.Join(decls, "\n")), nil, nil
+}
+
+// Returns the TypeScript type of a reference.
+func tsType(r *Ref) (name string) {
+	switch r.Type {
+	case Bool:
+		name = "boolean"
+	case Int, Float:
+		name = "number"
+	case String:
+		name = "string"
+		if len(r.Values) > 0 {
+			values := make([]string, len(r.Values))
+			for idx, v := range r.Values {
+				values[idx] = strconv.Quote(v)
+			}
+			name = strings.Join(values, " | ")
+		}
+	case Struct, Union:
+		name = r.Decl.Name
+	case Tuple:
+		var positions []string
+		for _, position := range r.Positions {
+			positions = append(positions, tsType(position))
+		}
+		name = "[" + strings.Join(positions, ", ") + "]"
+	default:
+		// The empty interface is a union of the kinds observed.
+		name = "unknown"
+		if len(r.Kinds) > 0 {
+			var names []string
+			for _, kind := range r.Kinds {
+				names = append(names, tsKinds[kind])
+			}
+			name = strings.Join(names, " | ")
+		}
+	}
+
+	if r.List {
+		if strings.Contains(name, " | ") {
+			name = "(" + name + ")"
+		}
+		name += "[]"
+	}
+	if r.Nullable && !strings.HasSuffix(name, "null") {
+		name += " | null"
+	}
+	return name
+}
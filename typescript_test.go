@@ -0,0 +1,116 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import "testing"
+
+func TestTypeScript(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.lang = "typescript"
+	})()
+
+	testCases := []struct {
+		Source   string
+		Expected string
+	}{
+		{`{"a": true, "b": 1, "c": 1.5, "d": "foo", "e": null}`,
+			"export interface Root {\n  a: boolean;\n  b: number;\n  c: number;\n  d: string;\n  e: null;\n}\n"},
+		{`[1, 2]`, "export type Root = number[];\n"},
+		{`[1, "foo", null]`, "export type Root = (number | string | null)[];\n"},
+		// Fields missing from some objects are optional, and fields which
+		// were null are nullable.
+		{`[{"a": 1, "b": null}, {"b": "foo"}]`,
+			"export type Root = RootElement[];\n\n" +
+				"export interface RootElement {\n  a?: number;\n  b: string | null;\n}\n"},
+		// Nested structs are named interfaces declared after their parent.
+		{`{"user": {"name": "foo", "address": {"city": "bar"}}, "title case": ""}`,
+			"export interface Root {\n  \"title case\": string;\n  user: User;\n}\n\n" +
+				"export interface User {\n  address: Address;\n  name: string;\n}\n\n" +
+				"export interface Address {\n  city: string;\n}\n"},
+		{`{"a": [], "b": {}}`,
+			"export interface Root {\n  a: unknown[];\n  b: B;\n}\n\nexport interface B {\n}\n"},
+	}
+
+	for _, testCase := range testCases {
+		tree, err := Parse(testCase.Source)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		formatted, _, err := typeScript{}.Emit(tree.Graph())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(formatted) != testCase.Expected {
+			t.Errorf("Source: %s Expected: %q Got: %q", testCase.Source, testCase.Expected, formatted)
+		}
+	}
+}
+
+// Tuples, unions, enums and recursive types have TypeScript equivalents.
+func TestTypeScriptTypes(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.lang = "typescript"
+		c.tuples = true
+		c.tupleMax = 8
+		c.unions = true
+		c.unionMax = 16
+		c.enum = true
+		c.enumMax = 10
+		c.enumSamples = 2
+	})()
+
+	testCases := []struct {
+		Source   string
+		Expected string
+	}{
+		{`{"price": ["EUR", 12.5]}`, "export interface Root {\n  price: [string, number];\n}\n"},
+		{`{"state": ["on", "off", "on"]}`, "export interface Root {\n  state: (\"off\" | \"on\")[];\n}\n"},
+		{`[{"type": "click", "x": 1}, {"type": "key", "code": "a"}, {"type": "key", "code": "b"}]`,
+			"export type Root = RootElement[];\n\n" +
+				"export type RootElement = RootElementClick | RootElementKey;\n\n" +
+				"export interface RootElementClick {\n  type: \"click\";\n  x: number;\n}\n\n" +
+				"export interface RootElementKey {\n  code: \"a\" | \"b\";\n  type: \"key\";\n}\n"},
+	}
+
+	for _, testCase := range testCases {
+		tree, err := Parse(testCase.Source)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		formatted, _, err := typeScript{}.Emit(tree.Graph())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(formatted) != testCase.Expected {
+			t.Errorf("Source: %s Expected: %q Got: %q", testCase.Source, testCase.Expected, formatted)
+		}
+	}
+
+	tree, err := Parse(`{"text": "a", "parent": {"text": "b", "parent": null}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree.DetectRecursion()
+
+	// The text of the parent is a value of the same field.
+	expected := "export interface Root {\n  parent: Root | null;\n  text: \"a\" | \"b\";\n}\n"
+	if formatted, _, _ := (typeScript{}).Emit(tree.Graph()); string(formatted) != expected {
+		t.Errorf("Expected: %q Got: %q", expected, formatted)
+	}
+}
@@ -0,0 +1,286 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Field names commonly used to discriminate variants, in order of preference.
+var discriminators = []string{"type", "kind", "event", "@type", "_type", "$type", "object"}
+
+// Returns the discriminator key of a struct or union, empty if it has none.
+// The key is config.unionKey if set, otherwise the first of discriminators
+// holding a string.
+func (t *Tree) discriminator() Ident {
+	if t.Type == Union {
+		return t.Discriminator
+	}
+
+	keys := discriminators
+	if config.unionKey != "" {
+		keys = []string{config.unionKey}
+	}
+
+	for _, key := range keys {
+		if _, ok := t.discriminated(Ident(key)); ok {
+			return Ident(key)
+		}
+	}
+	return ""
+}
+
+// Returns the value of a struct's discriminator if it only ever held one
+// string.
+func (t *Tree) discriminated(key Ident) (value Ident, ok bool) {
+	for _, child := range t.Children {
+		if child.Name != key {
+			continue
+		}
+
+		s := child.Stats
+		if child.List || child.Type != String || s == nil || len(s.Values) != 1 {
+			return "", false
+		}
+		for v := range s.Values {
+			return Ident(v), true
+		}
+	}
+	return "", false
+}
+
+// Returns the variants of a struct or union discriminated by key, a struct is
+// a single variant named by the value of its discriminator.
+func (t *Tree) variantsOf(key Ident) ([]*Tree, bool) {
+	if t.Type == Union {
+		return t.Children, t.Discriminator == key
+	}
+
+	value, ok := t.discriminated(key)
+	if t.Type != Struct || !ok {
+		return nil, false
+	}
+
+	variant := *t
+	variant.Name = value
+	variant.List = false
+	variant.Stats = t.values().copy()
+	return []*Tree{&variant}, true
+}
+
+// Squashes a struct or union element into the variant for its discriminator.
+// Returns false if the element has no discriminator, in which case the
+// variants squashed so far are squashed into one struct.
+func (e *elements) variant(element *Tree) bool {
+	if e.variants == nil {
+		if len(e.types) != 0 {
+			return false
+		}
+		if e.key = element.discriminator(); e.key == "" {
+			e.unions = false
+			return false
+		}
+		e.variants = make(map[Ident]*Tree)
+		e.types[Struct] = true
+	}
+
+	variants, ok := element.variantsOf(e.key)
+	if !ok {
+		e.collapse()
+		return false
+	}
+
+	for _, variant := range variants {
+		if existing, ok := e.variants[variant.Name]; ok {
+			e.squashStructs(existing, variant)
+		} else {
+			e.variants[variant.Name] = variant
+		}
+	}
+
+	// Too many variants aren't a union.
+	if len(e.variants) > config.unionMax {
+		e.collapse()
+	}
+	return true
+}
+
+// Returns true if the variants don't all have the same fields.
+func (e *elements) distinct() bool {
+	shapes := make(map[string]bool)
+	for _, variant := range e.variants {
+		var names []string
+		for _, child := range variant.Children {
+			names = append(names, string(child.Name))
+		}
+		shapes[strings.Join(names, "\x00")] = true
+	}
+	return len(e.variants) > 1 && len(shapes) > 1
+}
+
+// Squashes the variants into one struct, the elements aren't a union.
+func (e *elements) collapse() {
+	var names []string
+	for name := range e.variants {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+
+	variants := e.variants
+	e.unions = false
+	e.variants = nil
+	for _, name := range names {
+		variant := variants[Ident(name)]
+		e.signer.forget(variant)
+		variant.Name = ""
+		e.squash(variant)
+	}
+}
+
+// Turns a union into a struct of its squashed variants.
+func (e *elements) collapseUnion(t *Tree) {
+	variants := t.Children
+	for _, variant := range variants {
+		e.signer.forget(variant)
+		variant.Name = ""
+	}
+
+	t.Discriminator = ""
+	e.squashInto(t, variants...)
+}
+
+// Squashes a struct into another as if both were elements of the same list.
+func (e *elements) squashStructs(a, b *Tree) {
+	stats := mergeStats(a.Stats, b.Stats)
+	e.squashInto(a, a, b)
+	a.Stats = stats
+	e.signer.forgetTree(b)
+}
+
+// Sets the type and children of a tree to those of the structs squashed, the
+// name and stats of the tree are kept.
+func (e *elements) squashInto(t *Tree, structs ...*Tree) {
+	s := newElements(e.signer, false)
+	s.unions = false
+	for _, element := range structs {
+		s.squash(element)
+	}
+
+	stats := t.Stats
+	t.Stats = nil
+	s.finish(t)
+	t.Stats = stats
+	e.signer.forget(t)
+}
+
+// Merges two fields at least one of which is a union. Returns false if they
+// aren't variants with the same discriminator, in which case both are
+// structs.
+func (e *elements) mergeUnion(a, b *Tree) bool {
+	key := a.Discriminator
+	if key == "" {
+		key = b.Discriminator
+	}
+
+	av, aok := a.variantsOf(key)
+	bv, bok := b.variantsOf(key)
+	if !aok || !bok || a.List != b.List {
+		if a.Type == Union {
+			e.collapseUnion(a)
+		}
+		if b.Type == Union {
+			e.collapseUnion(b)
+		}
+		return false
+	}
+
+	variants := make(map[Ident]*Tree)
+	for _, variant := range av {
+		variants[variant.Name] = variant
+	}
+	for _, variant := range bv {
+		if existing, ok := variants[variant.Name]; ok {
+			e.squashStructs(existing, variant)
+		} else {
+			variants[variant.Name] = variant
+		}
+	}
+
+	a.Type = Union
+	a.Discriminator = key
+	a.Children = nil
+	for _, variant := range variants {
+		a.Children = append(a.Children, variant)
+	}
+	sort.Sort(a)
+	a.Stats = mergeStats(a.Stats, b.Stats)
+	e.signer.forget(a)
+
+	// Too many variants aren't a union.
+	if len(a.Children) > config.unionMax {
+		e.collapseUnion(a)
+	}
+	return true
+}
+
+// Declares a struct named after the field wrapping an interface implemented
+// by a struct for each variant of a union, and methods decoding the variant
+// named by the discriminator and encoding whichever variant it holds.
+func (f *formatter) union(t *Tree) string {
+	name := t.Name.String()
+	if name == "_" {
+		name = "Union"
+	}
+	name = f.names.declare(name)
+	iface := f.names.declare(name + "Variant")
+	key := t.Discriminator
+
+	u := unionSource{Name: name, Interface: iface, Key: string(key), Field: key.String()}
+	decl := f.execute("union.tmpl", u)
+
+	// Variants are declared at the top level, outside of the field being
+	// formatted.
+	for _, variant := range t.Children {
+		v := unionSource{Name: f.names.declare(name + constantSuffix(string(variant.Name))), Interface: iface, Value: string(variant.Name)}
+		s := &Tree{Name: Ident(v.Name), Type: Struct, Children: variant.Children, Stats: variant.Stats}
+
+		decl += "\n" + f.structDecl(v.Name, s, v.Name+" is "+name+" with "+u.Key+" "+strconv.Quote(v.Value)+".")
+		decl += "\n" + f.execute("variant", v)
+		u.Variants = append(u.Variants, v)
+	}
+
+	f.imports["encoding/json"] = true
+	f.imports["fmt"] = true
+
+	f.decls = append(f.decls, decl+"\n"+f.execute("union methods", u))
+
+	return name
+}
+
+// The names a union's declarations are written with: the union, its
+// interface and discriminator key, the field holding it and its variants.
+// Variants have the union's interface and their discriminator value.
+type unionSource struct {
+	Name      string
+	Interface string
+	Key       string
+	Field     string
+	Value     string
+	Variants  []unionSource
+}
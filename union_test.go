@@ -0,0 +1,281 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// Returns the names of the variants of a union.
+func variantNames(tree *Tree) (names []string) {
+	for _, variant := range tree.Children {
+		names = append(names, string(variant.Name))
+	}
+	return
+}
+
+func TestUnionDetect(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.unions = true
+		c.unionKey = ""
+		c.unionMax = 4
+	})()
+
+	testCases := []struct {
+		Source   string
+		Key      string
+		Variants []string
+	}{
+		{`[{"type": "a", "x": 1}, {"type": "b", "y": 1}, {"type": "a", "z": 1}]`, "type", []string{"a", "b"}},
+		{`[{"kind": "a", "x": 1}, {"kind": "b", "y": 1}]`, "kind", []string{"a", "b"}},
+		{`[{"kind": "a", "type": "b", "x": 1}, {"kind": "a", "type": "c", "y": 1}]`, "type", []string{"b", "c"}},
+		// Variants of the same shape aren't a union.
+		{`[{"type": "a", "x": 1}, {"type": "b", "x": 2}]`, "", nil},
+		// Neither are structs without a discriminator, one variant or too
+		// many variants.
+		{`[{"type": "a", "x": 1}, {"y": 1}]`, "", nil},
+		{`[{"type": 1, "x": 1}, {"type": 2, "y": 1}]`, "", nil},
+		{`[{"type": "a", "x": 1}, {"type": "a", "y": 1}]`, "", nil},
+		{`[{"type": "a", "a": 1}, {"type": "b", "b": 1}, {"type": "c", "c": 1}, {"type": "d", "d": 1}, {"type": "e", "e": 1}]`, "", nil},
+	}
+
+	for _, testCase := range testCases {
+		tree, err := Parse(testCase.Source)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if testCase.Key == "" {
+			if tree.Type != Struct {
+				t.Errorf("Source: %s Expected: %s Got: %s", testCase.Source, Struct, tree.Type)
+			}
+			continue
+		}
+
+		if tree.Type != Union || string(tree.Discriminator) != testCase.Key {
+			t.Errorf("Source: %s Expected: %s Got: %s %s", testCase.Source, testCase.Key, tree.Type, tree.Discriminator)
+			continue
+		}
+
+		if names := variantNames(&tree); !reflect.DeepEqual(names, testCase.Variants) {
+			t.Errorf("Source: %s Expected: %q Got: %q", testCase.Source, testCase.Variants, names)
+		}
+	}
+}
+
+func TestUnionKey(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.unions = true
+		c.unionKey = "op"
+		c.unionMax = 4
+	})()
+
+	tree, err := Parse(`[{"op": "add", "type": "x", "value": 1}, {"op": "remove", "type": "x", "path": "/a"}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tree.Type != Union || tree.Discriminator != "op" {
+		t.Fatalf("Expected: union op Got: %s %s", tree.Type, tree.Discriminator)
+	}
+	if names := variantNames(&tree); !reflect.DeepEqual(names, []string{"add", "remove"}) {
+		t.Errorf("Expected: %q Got: %q", []string{"add", "remove"}, names)
+	}
+}
+
+// Unions nested in lists of structs, or in separate inputs, are merged.
+func TestUnionMerge(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.unions = true
+		c.unionKey = ""
+		c.unionMax = 4
+	})()
+
+	tree, err := Parse(`[
+		{"events": [{"type": "a", "x": 1}, {"type": "b", "y": 1}]},
+		{"events": [{"type": "c", "z": 1}, {"type": "a", "x": 2, "w": 1}]},
+		{"events": [{"type": "a", "x": 3}]}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := tree.Children[0]
+	if events.Type != Union || !events.List {
+		t.Fatalf("Expected: []union Got: %+v", events)
+	}
+	if names := variantNames(events); !reflect.DeepEqual(names, []string{"a", "b", "c"}) {
+		t.Errorf("Expected: %q Got: %q", []string{"a", "b", "c"}, names)
+	}
+	if a := events.Children[0]; len(a.Children) != 3 {
+		t.Errorf("Expected: 3 fields Got: %+v", a.Children)
+	}
+
+	var trees []*Tree
+	for _, source := range []string{`[{"type": "a", "x": 1}]`, `[{"type": "b", "y": 1}, {"type": "a", "x": 2}]`} {
+		tree, err := Parse(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		trees = append(trees, &tree)
+	}
+
+	merged := Merge(trees...)
+	if merged.Type != Union || !reflect.DeepEqual(variantNames(&merged), []string{"a", "b"}) {
+		t.Errorf("Expected: union of a and b Got: %+v", merged)
+	}
+}
+
+// Unions must be inferred the same way while streaming.
+func TestUnionInfer(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.unions = true
+		c.unionKey = ""
+		c.unionMax = 4
+	})()
+
+	sources := []string{
+		`[{"type": "a", "x": 1}, {"type": "b", "y": 1}, {"type": "a", "z": 1}]`,
+		`[{"type": "a", "x": 1}, {"type": "b", "x": 2}]`,
+		`[{"type": "a", "x": 1}, {"y": 1}, {"type": "b", "z": 1}]`,
+		`[{"e": [{"type": "a", "x": 1}, {"type": "b", "y": 1}]}, {"e": [{"type": "c", "z": 1}]}]`,
+	}
+
+	for _, source := range sources {
+		expected, err := Parse(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var tree Tree
+		if err := tree.Infer(json.NewDecoder(bytes.NewBufferString(source))); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(tree, expected) {
+			t.Errorf("Source: %s Expected: %+v Got: %+v", source, expected, tree)
+		}
+	}
+}
+
+func TestUnionFormat(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.unions = true
+		c.unionKey = ""
+		c.unionMax = 4
+	})()
+
+	tree, err := Parse(`{"events": [{"type": "click", "x": 1}, {"type": "key", "code": "a"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	formatted, err := tree.Format()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "import (\n" +
+		"\t\"encoding/json\"\n" +
+		"\t\"fmt\"\n" +
+		")\n" +
+		"\n" +
+		"type _ struct {\n" +
+		"\tEvents []Events `json:\"events\"`\n" +
+		"}\n" +
+		"\n" +
+		"type Events struct {\n" +
+		"\tEventsVariant\n" +
+		"}\n" +
+		"\n" +
+		"type EventsVariant interface {\n" +
+		"\tisEventsVariant()\n" +
+		"}\n" +
+		"\n" +
+		"// EventsClick is Events with type \"click\".\n" +
+		"type EventsClick struct {\n" +
+		"\tType string `json:\"type\"`\n" +
+		"\tX    int64  `json:\"x\"`\n" +
+		"}\n" +
+		"\n" +
+		"func (EventsClick) isEventsVariant() {}\n" +
+		"\n" +
+		"// EventsKey is Events with type \"key\".\n" +
+		"type EventsKey struct {\n" +
+		"\tCode string `json:\"code\"`\n" +
+		"\tType string `json:\"type\"`\n" +
+		"}\n" +
+		"\n" +
+		"func (EventsKey) isEventsVariant() {}\n" +
+		"\n" +
+		"func (v *Events) UnmarshalJSON(data []byte) error {\n" +
+		"\tvar discriminator struct {\n" +
+		"\t\tType string `json:\"type\"`\n" +
+		"\t}\n" +
+		"\tif err := json.Unmarshal(data, &discriminator); err != nil {\n" +
+		"\t\treturn err\n" +
+		"\t}\n" +
+		"\n" +
+		"\tswitch discriminator.Type {\n" +
+		"\tcase \"click\":\n" +
+		"\t\tv.EventsVariant = new(EventsClick)\n" +
+		"\tcase \"key\":\n" +
+		"\t\tv.EventsVariant = new(EventsKey)\n" +
+		"\tdefault:\n" +
+		"\t\treturn fmt.Errorf(\"unknown Events type %q\", discriminator.Type)\n" +
+		"\t}\n" +
+		"\n" +
+		"\treturn json.Unmarshal(data, v.EventsVariant)\n" +
+		"}\n" +
+		"\n" +
+		"func (v Events) MarshalJSON() ([]byte, error) {\n" +
+		"\treturn json.Marshal(v.EventsVariant)\n" +
+		"}\n"
+
+	if string(formatted) != expected {
+		t.Errorf("Expected: %q Got: %q", expected, formatted)
+	}
+}
+
+func TestUnionValidate(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.unions = true
+		c.unionKey = ""
+		c.unionMax = 4
+	})()
+
+	sample := `[{"type": "a", "x": 1}, {"type": "b", "y": "foo"}]`
+	testCases := []ValidateTestCase{
+		{sample, `[{"type": "b", "y": "bar"}, {"type": "a", "x": 2}]`, nil},
+		{sample, `[{"type": "a", "y": "bar"}]`, []ValidationError{
+			{".[0].y", UnknownKey, "no field for key"},
+			{".[0].x", MissingField, "expected int64"},
+		}},
+		{sample, `[{"type": "c"}, {"x": 1}, {"type": 1}]`, []ValidationError{
+			{".[0].type", TypeMismatch, "unknown variant \"c\""},
+			{".[1].type", MissingField, "expected string"},
+			{".[2].type", TypeMismatch, "expected string, got number"},
+		}},
+	}
+
+	for _, testCase := range testCases {
+		testCase.TestValidate(t)
+	}
+}
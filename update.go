@@ -0,0 +1,393 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Comment placed above fields of an existing type which don't appear in the
+// sample. Fields are flagged rather than removed so hand edits aren't lost.
+const removedMarker = "// JSONGen: not present in sample."
+
+// An insertion of text at an offset of the original source.
+type edit struct {
+	offset int
+	text   string
+}
+
+// Walks the declarations of an existing source file alongside a tree,
+// collecting the edits required to bring the declarations up to date.
+type updater struct {
+	fset *token.FileSet
+
+	// Formats new fields and collects the declarations they require.
+	f *formatter
+
+	// Named types declared in the file, so fields referring to hand-written
+	// named types can be followed.
+	types map[string]*ast.TypeSpec
+
+	// Structs which have already been updated, named types may be shared.
+	visited map[*ast.StructType]bool
+
+	edits   []edit
+	removed []string
+}
+
+// Updates the struct type named typeName declared in src with fields found
+// in the tree. Fields are matched by their json tag, or by name if they have
+// none. Fields missing from the type are added, fields missing from the tree
+// are flagged with a comment and returned by their JSON path. Field names,
+// comments, types and any other declarations are left as they are. If
+// typeName is empty the first struct type in the file is updated.
+func Update(filename string, src []byte, typeName string, t *Tree) (updated []byte, removed []string, err error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return
+	}
+
+	u := updater{
+		fset:    fset,
+		f:       newFormatter(),
+		types:   make(map[string]*ast.TypeSpec),
+		visited: make(map[*ast.StructType]bool),
+	}
+
+	// Find the type to update and make note of all named types.
+	var spec *ast.TypeSpec
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+
+		for _, s := range gen.Specs {
+			ts := s.(*ast.TypeSpec)
+			u.types[ts.Name.Name] = ts
+			u.f.names.taken[ts.Name.Name] = true
+
+			// Types declared for detectors by an earlier run are reused.
+			for _, d := range detectors {
+				if d.Declaration != "" && d.Type == ts.Name.Name {
+					u.f.detectorTypes[d.Name] = ts.Name.Name
+				}
+			}
+
+			if spec != nil {
+				continue
+			}
+			if typeName == ts.Name.Name || typeName == "" && u.structType(ts.Type) != nil {
+				spec = ts
+			}
+		}
+	}
+
+	if spec == nil || u.structType(spec.Type) == nil {
+		if typeName == "" {
+			return nil, nil, fmt.Errorf("update: no struct type declared in %s", filename)
+		}
+		return nil, nil, fmt.Errorf("update: no struct type %q declared in %s", typeName, filename)
+	}
+
+	if t.Type != Struct {
+		return nil, nil, fmt.Errorf("update: sample is %s, not an object", t.Type)
+	}
+
+	u.f.reference(t)
+	u.update(spec.Type, t, ".")
+	u.declare(file, len(src))
+
+	// Apply edits from the end of the file so offsets remain valid, edits at
+	// the same offset are inserted in the order they were made.
+	sort.Stable(byOffset(u.edits))
+	for idx := len(u.edits) - 1; idx >= 0; idx-- {
+		e := u.edits[idx]
+		src = append(src[:e.offset:e.offset], append([]byte(e.text), src[e.offset:]...)...)
+	}
+
+	updated, err = format.Source(src)
+	return updated, u.removed, err
+}
+
+type byOffset []edit
+
+func (e byOffset) Len() int           { return len(e) }
+func (e byOffset) Less(i, j int) bool { return e[i].offset < e[j].offset }
+func (e byOffset) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+
+// Returns the struct described by a type expression, looking through
+// pointers, slices, arrays and named types declared in the file.
+func (u *updater) structType(expr ast.Expr) *ast.StructType {
+	// Limit the number of named types followed in case of cycles.
+	for depth := 0; depth < 16; depth++ {
+		switch e := expr.(type) {
+		case *ast.StructType:
+			return e
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ArrayType:
+			expr = e.Elt
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.Ident:
+			spec, ok := u.types[e.Name]
+			if !ok {
+				return nil
+			}
+			expr = spec.Type
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// A field of a struct and the name encoding/json knows it by.
+type jsonField struct {
+	field *ast.Field
+	name  string
+}
+
+// Returns the fields of a struct visible to encoding/json, including those
+// promoted from embedded structs declared in the file.
+func (u *updater) fields(st *ast.StructType) (fields []jsonField) {
+	for _, field := range st.Fields.List {
+		tag := ""
+		if field.Tag != nil {
+			if value, err := strconv.Unquote(field.Tag.Value); err == nil {
+				tag = reflect.StructTag(value).Get("json")
+			}
+		}
+
+		name := strings.Split(tag, ",")[0]
+		if name == "-" && tag == "-" {
+			continue
+		}
+
+		// Embedded structs without a name in their tag promote their
+		// fields, others are fields named after their type.
+		names := field.Names
+		if len(names) == 0 {
+			if embedded := u.structType(field.Type); embedded != nil && name == "" {
+				fields = append(fields, u.fields(embedded)...)
+				continue
+			}
+			if ident := embeddedName(field.Type); ident != nil && name != "" {
+				names = []*ast.Ident{ident}
+			}
+		}
+
+		for _, ident := range names {
+			if !ident.IsExported() {
+				continue
+			}
+			if name == "" {
+				fields = append(fields, jsonField{field, ident.Name})
+			} else {
+				fields = append(fields, jsonField{field, name})
+			}
+		}
+	}
+	return
+}
+
+// Returns the name of the field of an embedded type, nil if it isn't a
+// named type.
+func embeddedName(expr ast.Expr) *ast.Ident {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e
+	case *ast.StarExpr:
+		return embeddedName(e.X)
+	case *ast.SelectorExpr:
+		return e.Sel
+	}
+	return nil
+}
+
+// Returns the Go names of the fields of a struct, including those of
+// embedded types.
+func goNames(st *ast.StructType) *namer {
+	names := newNamer("")
+	for _, field := range st.Fields.List {
+		for _, ident := range field.Names {
+			names.taken[ident.Name] = true
+		}
+		if ident := embeddedName(field.Type); len(field.Names) == 0 && ident != nil {
+			names.taken[ident.Name] = true
+		}
+	}
+	return names
+}
+
+// Recursively matches the fields of a struct type with the children of a
+// tree, recording edits for new and removed fields.
+func (u *updater) update(expr ast.Expr, t *Tree, path string) {
+	st := u.structType(expr)
+	if st == nil || t.Type != Struct || u.visited[st] {
+		return
+	}
+	u.visited[st] = true
+
+	unmatched := make(map[*Tree]bool)
+	for _, child := range t.Children {
+		unmatched[child] = true
+	}
+
+	for _, f := range u.fields(st) {
+		// Prefer an exact match, encoding/json falls back to case-insensitive.
+		var match *Tree
+		for _, child := range t.Children {
+			if unmatched[child] && string(child.Name) == f.name {
+				match = child
+				break
+			}
+		}
+		for _, child := range t.Children {
+			if match == nil && unmatched[child] && strings.EqualFold(string(child.Name), f.name) {
+				match = child
+			}
+		}
+
+		if match == nil {
+			u.flag(f.field, joinPath(path, f.name))
+			continue
+		}
+
+		delete(unmatched, match)
+		u.update(f.field.Type, match, joinPath(path, f.name))
+	}
+
+	// New fields are appended in the order they would be generated, numbered
+	// if their name is taken by another field.
+	var text string
+	u.f.path = path
+	names := goNames(st)
+	for _, child := range t.Children {
+		if !unmatched[child] {
+			continue
+		}
+
+		text += u.f.fieldSource(child, t, names.declare(child.Name.String()))
+	}
+	if text == "" {
+		return
+	}
+
+	// Make sure new fields start on a line of their own.
+	closing := u.fset.Position(st.Fields.Closing)
+	last := u.fset.Position(st.Fields.Opening)
+	if n := len(st.Fields.List); n > 0 {
+		last = u.fset.Position(st.Fields.List[n-1].End())
+	}
+	if last.Line == closing.Line {
+		text = "\n" + text
+	}
+
+	u.edits = append(u.edits, edit{closing.Offset, text})
+}
+
+// Appends declarations required by new fields to the end of the file and
+// imports any packages they require which aren't already imported.
+func (u *updater) declare(file *ast.File, end int) {
+	for _, decl := range u.f.decls {
+		u.edits = append(u.edits, edit{end, "\n" + decl})
+	}
+
+	imported := make(map[string]bool)
+	for _, spec := range file.Imports {
+		if path, err := strconv.Unquote(spec.Path.Value); err == nil {
+			imported[path] = true
+		}
+	}
+
+	var paths []string
+	for path := range u.f.imports {
+		if !imported[path] {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	offset := u.fset.Position(file.Name.End()).Offset
+	for _, path := range paths {
+		u.edits = append(u.edits, edit{offset, "\n\nimport " + strconv.Quote(path)})
+	}
+}
+
+// Flags a field which wasn't found in the tree, unless it already is.
+func (u *updater) flag(field *ast.Field, path string) {
+	u.removed = append(u.removed, path)
+
+	if field.Doc != nil {
+		for _, comment := range field.Doc.List {
+			if comment.Text == removedMarker {
+				return
+			}
+		}
+	}
+
+	// Fields with several names are only flagged once.
+	offset := u.fset.Position(field.Pos()).Offset
+	for _, e := range u.edits {
+		if e.offset == offset {
+			return
+		}
+	}
+
+	u.edits = append(u.edits, edit{offset, removedMarker + "\n"})
+}
+
+// Updates the file given on the command line in place.
+func updateType(t *Tree) {
+	info, err := os.Stat(config.updateFilename)
+	if err != nil {
+		log.Fatal("Error reading source:", err)
+	}
+
+	src, err := ioutil.ReadFile(config.updateFilename)
+	if err != nil {
+		log.Fatal("Error reading source:", err)
+	}
+
+	updated, removed, err := Update(config.updateFilename, src, config.updateType, t)
+	if err != nil {
+		log.Fatal("Error updating source:", err)
+	}
+
+	for _, path := range removed {
+		log.Printf("Field %s not present in sample.\n", path)
+	}
+
+	err = ioutil.WriteFile(config.updateFilename, updated, info.Mode())
+	if err != nil {
+		log.Fatal("Error writing source:", err)
+	}
+}
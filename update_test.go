@@ -0,0 +1,223 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+type UpdateTestCase struct {
+	Source, Sample, TypeName string
+	Updated                  string
+	Removed                  []string
+}
+
+func (tc UpdateTestCase) TestUpdate(t *testing.T) {
+	tree, err := Parse(tc.Sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated, removed, err := Update("test.go", []byte(tc.Source), tc.TypeName, &tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(updated) != tc.Updated {
+		t.Errorf("Expected: %q Got: %q", tc.Updated, updated)
+	}
+
+	if !reflect.DeepEqual(removed, tc.Removed) {
+		t.Errorf("Expected: %q Got: %q", tc.Removed, removed)
+	}
+}
+
+func TestUpdateAdd(t *testing.T) {
+	testCases := []UpdateTestCase{
+		{
+			Source:  "package foo\n\ntype Foo struct {\n\tID int64 `json:\"id\"`\n}\n",
+			Sample:  `{"id": 1, "name": "foo"}`,
+			Updated: "package foo\n\ntype Foo struct {\n\tID   int64  `json:\"id\"`\n\tName string `json:\"name\"`\n}\n",
+		},
+		{
+			Source:  "package foo\n\ntype Foo struct{}\n",
+			Sample:  `{"id": 1}`,
+			Updated: "package foo\n\ntype Foo struct {\n\tId int64 `json:\"id\"`\n}\n",
+		},
+		{
+			Source:  "package foo\n\ntype Foo struct{ ID int64 }\n",
+			Sample:  `{"id": 1, "name": "foo"}`,
+			Updated: "package foo\n\ntype Foo struct {\n\tID   int64\n\tName string `json:\"name\"`\n}\n",
+		},
+		// New fields are renamed rather than repeat the name of a field
+		// known by another key.
+		{
+			Source:  "package foo\n\ntype Foo struct {\n\tName string `json:\"full_name\"`\n}\n",
+			Sample:  `{"full_name": "a b", "name": "a", "Name": "b"}`,
+			Updated: "package foo\n\ntype Foo struct {\n\tName  string `json:\"full_name\"`\n\tName2 string `json:\"Name\"`\n\tName3 string `json:\"name\"`\n}\n",
+		},
+		// Embedded types with a name in their tag are fields of that name.
+		{
+			Source:   "package foo\n\ntype Base struct {\n\tID int64 `json:\"id\"`\n}\n\ntype Foo struct {\n\tBase `json:\"base\"`\n}\n",
+			Sample:   `{"base": {"id": 1}, "id": 2}`,
+			TypeName: "Foo",
+			Updated:  "package foo\n\ntype Base struct {\n\tID int64 `json:\"id\"`\n}\n\ntype Foo struct {\n\tBase `json:\"base\"`\n\tId   int64 `json:\"id\"`\n}\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase.TestUpdate(t)
+	}
+}
+
+func TestUpdatePreserve(t *testing.T) {
+	testCases := []UpdateTestCase{
+		// Hand-written names, comments, types and methods are kept.
+		{
+			Source: `package foo
+
+// Foo is a foo.
+type Foo struct {
+	// Identifies the foo.
+	Identifier string ` + "`json:\"id,omitempty\"`" + ` // Numeric in practice.
+}
+
+func (f Foo) String() string {
+	return f.Identifier
+}
+`,
+			Sample: `{"id": 1}`,
+			Updated: `package foo
+
+// Foo is a foo.
+type Foo struct {
+	// Identifies the foo.
+	Identifier string ` + "`json:\"id,omitempty\"`" + ` // Numeric in practice.
+}
+
+func (f Foo) String() string {
+	return f.Identifier
+}
+`,
+		},
+		// The requested type is updated, nested named types are followed.
+		{
+			Source: `package foo
+
+type Bar struct {
+	ID int64
+}
+
+type Foo struct {
+	Bar  *Bar    ` + "`json:\"bar\"`" + `
+	Bars []Bar   ` + "`json:\"bars\"`" + `
+	Baz  struct{} ` + "`json:\"baz\"`" + `
+}
+`,
+			TypeName: "Foo",
+			Sample:   `{"bar": {"id": 1, "name": "bar"}, "bars": [{"id": 1}], "baz": {"qux": true}}`,
+			Updated: `package foo
+
+type Bar struct {
+	ID   int64
+	Name string ` + "`json:\"name\"`" + `
+}
+
+type Foo struct {
+	Bar  *Bar  ` + "`json:\"bar\"`" + `
+	Bars []Bar ` + "`json:\"bars\"`" + `
+	Baz  struct {
+		Qux bool ` + "`json:\"qux\"`" + `
+	} ` + "`json:\"baz\"`" + `
+}
+`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase.TestUpdate(t)
+	}
+}
+
+func TestUpdateRemoved(t *testing.T) {
+	testCases := []UpdateTestCase{
+		{
+			Source: `package foo
+
+type Foo struct {
+	ID int64 ` + "`json:\"id\"`" + `
+	// The name of the foo.
+	Name     string ` + "`json:\"name\"`" + `
+	internal bool
+	Ignored  bool ` + "`json:\"-\"`" + `
+}
+`,
+			Sample: `{"id": 1}`,
+			Updated: `package foo
+
+type Foo struct {
+	ID int64 ` + "`json:\"id\"`" + `
+	// The name of the foo.
+	// JSONGen: not present in sample.
+	Name     string ` + "`json:\"name\"`" + `
+	internal bool
+	Ignored  bool ` + "`json:\"-\"`" + `
+}
+`,
+			Removed: []string{".name"},
+		},
+		// Fields which are already flagged aren't flagged again.
+		{
+			Source: `package foo
+
+type Foo struct {
+	// JSONGen: not present in sample.
+	Name string ` + "`json:\"name\"`" + `
+}
+`,
+			Sample: `{}`,
+			Updated: `package foo
+
+type Foo struct {
+	// JSONGen: not present in sample.
+	Name string ` + "`json:\"name\"`" + `
+}
+`,
+			Removed: []string{".name"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase.TestUpdate(t)
+	}
+}
+
+func TestUpdateNoType(t *testing.T) {
+	tree, err := Parse(`{}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := Update("test.go", []byte("package foo\n\ntype Foo int\n"), "", &tree); err == nil {
+		t.Errorf("Expected error updating file without struct types.")
+	}
+
+	if _, _, err := Update("test.go", []byte("package foo\n\ntype Foo struct{}\n"), "Bar", &tree); err == nil {
+		t.Errorf("Expected error updating undeclared type.")
+	}
+}
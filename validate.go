@@ -0,0 +1,304 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// Loads a tree previously written with -dump.
+func LoadTree(filename string, t *Tree) error {
+	dumpFile, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer dumpFile.Close()
+
+	return json.NewDecoder(dumpFile).Decode(t)
+}
+
+// Kinds of problems found while validating a document against a tree.
+type Violation int
+
+const (
+	UnknownKey Violation = iota + 1
+	MissingField
+	TypeMismatch
+	NullValue
+)
+
+func (v Violation) String() string {
+	switch v {
+	case UnknownKey:
+		return "unknown key"
+	case MissingField:
+		return "missing field"
+	case TypeMismatch:
+		return "type mismatch"
+	case NullValue:
+		return "null value"
+	}
+	return "unset"
+}
+
+// A problem found at a JSON path of a document, elements of lists are written
+// as .a[0].b.
+type ValidationError struct {
+	Path      string
+	Violation Violation
+	Message   string
+}
+
+func (e ValidationError) Error() string {
+	return e.Path + ": " + e.Violation.String() + ": " + e.Message
+}
+
+// Checks that a value which JSON has been parsed into would decode into the
+// type described by the tree without losing anything. Values must be parsed
+// with UseNumber so integers can be told apart from floats.
+func (t *Tree) Validate(v interface{}) (errs []ValidationError) {
+	vr := &validation{targets: t.targets()}
+	t.validate(v, ".", t.List, vr)
+	return vr.errs
+}
+
+// Problems found so far and the structs recursive types refer to, by path.
+type validation struct {
+	errs    []ValidationError
+	targets map[string]*Tree
+}
+
+func (t *Tree) validate(v interface{}, path string, list bool, vr *validation) {
+	fail := func(violation Violation, format string, args ...interface{}) {
+		vr.errs = append(vr.errs, ValidationError{path, violation, fmt.Sprintf(format, args...)})
+	}
+
+	// Pointers hold null whatever they point to.
+	if v == nil && t.Nullable {
+		return
+	}
+
+	// Recursive types are validated as the struct they refer to.
+	if t.Type == Recursive {
+		if target, ok := vr.targets[t.Ref]; ok {
+			target.validate(v, path, list, vr)
+		}
+		return
+	}
+
+	// Only the empty interface can hold null without losing it.
+	if v == nil {
+		if t.Type != Interface {
+			fail(NullValue, "expected %s", t.goType(list))
+		}
+		return
+	}
+
+	if list {
+		elements, ok := v.([]interface{})
+		if !ok {
+			fail(TypeMismatch, "expected %s, got %s", t.goType(list), jsonKind(v))
+			return
+		}
+
+		for idx, element := range elements {
+			t.validate(element, path+"["+strconv.Itoa(idx)+"]", false, vr)
+		}
+		return
+	}
+
+	ok := false
+	switch t.Type {
+	case Interface:
+		ok = true
+	case Bool:
+		_, ok = v.(bool)
+	case Int:
+		// Integers beyond int64 are allowed if the tree's range includes
+		// them, they're decoded as some other type.
+		if n, isNumber := v.(json.Number); isNumber {
+			_, err := n.Int64()
+			_, isInteger := integer(n)
+			ok = err == nil || isInteger && t.big()
+		}
+	case Float:
+		_, ok = v.(json.Number)
+	case String:
+		// Strings of detected types which aren't strings must match.
+		var s string
+		if s, ok = v.(string); ok {
+			if d := t.detector(); d != nil && d.goType() != "string" && !d.Match(s) {
+				fail(TypeMismatch, "expected %s, got %q", d.Description, s)
+				return
+			}
+		}
+	case Struct:
+		var object map[string]interface{}
+		if object, ok = v.(map[string]interface{}); ok {
+			t.validateStruct(object, path, vr)
+		}
+	case Union:
+		var object map[string]interface{}
+		if object, ok = v.(map[string]interface{}); ok {
+			t.validateUnion(object, path, vr)
+		}
+	case Tuple:
+		var elements []interface{}
+		if elements, ok = v.([]interface{}); !ok {
+			break
+		}
+
+		if len(elements) != len(t.Children) {
+			fail(TypeMismatch, "expected %d elements, got %d", len(t.Children), len(elements))
+			return
+		}
+		for idx, position := range t.Children {
+			position.validate(elements[idx], path+"["+strconv.Itoa(idx)+"]", position.List, vr)
+		}
+	}
+
+	if !ok {
+		fail(TypeMismatch, "expected %s, got %s", t.goType(list), jsonKind(v))
+	}
+}
+
+func (t *Tree) validateStruct(object map[string]interface{}, path string, vr *validation) {
+	fields := make(map[string]*Tree)
+	for _, child := range t.Children {
+		fields[string(child.Name)] = child
+	}
+
+	// Sort keys for consistent output.
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		child, ok := fields[key]
+		if !ok {
+			vr.errs = append(vr.errs, ValidationError{joinPath(path, key), UnknownKey, "no field for key"})
+			continue
+		}
+		child.validate(object[key], joinPath(path, key), child.List, vr)
+	}
+
+	// Fields missing from some of the objects observed may be missing.
+	for _, child := range t.Children {
+		if _, ok := object[string(child.Name)]; !ok && !child.optional(t) {
+			vr.errs = append(vr.errs, ValidationError{joinPath(path, string(child.Name)), MissingField, "expected " + child.goType(child.List)})
+		}
+	}
+}
+
+// Validates an object against the variant named by its discriminator.
+func (t *Tree) validateUnion(object map[string]interface{}, path string, vr *validation) {
+	key := string(t.Discriminator)
+	value, ok := object[key].(string)
+	if !ok {
+		if _, exists := object[key]; exists {
+			vr.errs = append(vr.errs, ValidationError{joinPath(path, key), TypeMismatch, "expected string, got " + jsonKind(object[key])})
+		} else {
+			vr.errs = append(vr.errs, ValidationError{joinPath(path, key), MissingField, "expected string"})
+		}
+		return
+	}
+
+	for _, variant := range t.Children {
+		if string(variant.Name) == value {
+			variant.validateStruct(object, path, vr)
+			return
+		}
+	}
+	vr.errs = append(vr.errs, ValidationError{joinPath(path, key), TypeMismatch, fmt.Sprintf("unknown variant %q", value)})
+}
+
+// Returns true if the integer range of the tree exceeds int64.
+func (t *Tree) big() bool {
+	s := t.values()
+	return s != nil && s.Min != nil && !(s.Min.IsInt64() && s.Max.IsInt64())
+}
+
+// Returns the Go type of a node for messages, anonymous structs are
+// abbreviated.
+func (t *Tree) goType(list bool) string {
+	if list {
+		return "[]" + t.Type.String()
+	}
+	return t.Type.String()
+}
+
+// Describes the JSON type of a value which JSON has been parsed into.
+func jsonKind(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case json.Number, float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// Validates each of the named files against the tree, reporting problems on
+// stderr. Returns true if every file is valid.
+func validateFiles(t *Tree, filenames []string) (valid bool) {
+	valid = true
+	for _, filename := range filenames {
+		errs, err := validateFile(t, filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", filename, err)
+			valid = false
+			continue
+		}
+
+		for _, e := range errs {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", filename, e)
+			valid = false
+		}
+	}
+	return
+}
+
+func validateFile(t *Tree, filename string) ([]ValidationError, error) {
+	inputFile, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer inputFile.Close()
+
+	jsonDecoder := json.NewDecoder(inputFile)
+	jsonDecoder.UseNumber()
+	var data interface{}
+	if err := jsonDecoder.Decode(&data); err != nil {
+		return nil, err
+	}
+
+	return t.Validate(data), nil
+}
@@ -0,0 +1,145 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+type ValidateTestCase struct {
+	Sample, Document string
+	Errors           []ValidationError
+}
+
+func (tc ValidateTestCase) TestValidate(t *testing.T) {
+	tree, err := Parse(tc.Sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var data interface{}
+	jsonDecoder := json.NewDecoder(bytes.NewBufferString(tc.Document))
+	jsonDecoder.UseNumber()
+	if err := jsonDecoder.Decode(&data); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := tree.Validate(data)
+	if !reflect.DeepEqual(errs, tc.Errors) {
+		t.Errorf("Document: %s Expected: %+v Got: %+v", tc.Document, tc.Errors, errs)
+	}
+}
+
+func TestValidatePrimitive(t *testing.T) {
+	testCases := []ValidateTestCase{
+		{`null`, `1`, nil},
+		{`true`, `false`, nil},
+		{`1`, `2`, nil},
+		{`1.0`, `2`, nil},
+		{`"foo"`, `"bar"`, nil},
+		{`true`, `"true"`, []ValidationError{{".", TypeMismatch, "expected bool, got string"}}},
+		{`1`, `1.5`, []ValidationError{{".", TypeMismatch, "expected int64, got number"}}},
+		{`"foo"`, `null`, []ValidationError{{".", NullValue, "expected string"}}},
+	}
+
+	for _, testCase := range testCases {
+		testCase.TestValidate(t)
+	}
+}
+
+func TestValidateList(t *testing.T) {
+	testCases := []ValidateTestCase{
+		{`[1, 2]`, `[]`, nil},
+		{`[1, 2]`, `[3]`, nil},
+		{`[1, 2]`, `3`, []ValidationError{{".", TypeMismatch, "expected []int64, got number"}}},
+		{`[1, 2]`, `[3, "4", null]`, []ValidationError{
+			{".[1]", TypeMismatch, "expected int64, got string"},
+			{".[2]", NullValue, "expected int64"},
+		}},
+		{`[true, 1]`, `[false, "foo", null]`, nil},
+	}
+
+	for _, testCase := range testCases {
+		testCase.TestValidate(t)
+	}
+}
+
+func TestValidateStruct(t *testing.T) {
+	testCases := []ValidateTestCase{
+		{`{"a": 1, "b": {"c": "foo"}}`, `{"a": 2, "b": {"c": "bar"}}`, nil},
+		{`{"a": 1, "b": {"c": "foo"}}`, `{"a": 2, "b": {"c": "bar", "d": 1}, "e": true}`, []ValidationError{
+			{".b.d", UnknownKey, "no field for key"},
+			{".e", UnknownKey, "no field for key"},
+		}},
+		{`{"a": 1, "b": {"c": "foo"}}`, `{"b": {}}`, []ValidationError{
+			{".b.c", MissingField, "expected string"},
+			{".a", MissingField, "expected int64"},
+		}},
+		{`{"a": [{"b": 1}]}`, `{"a": [{"b": 1}, {"b": "foo"}, []]}`, []ValidationError{
+			{".a[1].b", TypeMismatch, "expected int64, got string"},
+			{".a[2]", TypeMismatch, "expected struct, got array"},
+		}},
+	}
+
+	for _, testCase := range testCases {
+		testCase.TestValidate(t)
+	}
+}
+
+func TestValidateOptional(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.command = "validate"
+	})()
+
+	testCases := []ValidateTestCase{
+		// Fields missing from some of the objects observed may be missing.
+		{`[{"a": 1, "b": 2}, {"a": 3}]`, `[{"a": 4}, {"b": 5}]`, []ValidationError{
+			{".[1].a", MissingField, "expected int64"},
+		}},
+		{`[{"a": 1, "b": 2}, {"a": 3}]`, `[{"a": 1, "b": 2}, {"a": 3}]`, nil},
+	}
+
+	for _, testCase := range testCases {
+		testCase.TestValidate(t)
+	}
+}
+
+func TestTypeText(t *testing.T) {
+	for typ := Interface; typ <= Tuple; typ++ {
+		text, err := typ.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var parsed Type
+		if err := parsed.UnmarshalText(text); err != nil {
+			t.Fatal(err)
+		}
+
+		if parsed != typ {
+			t.Errorf("Expected: %s Got: %s", typ, parsed)
+		}
+	}
+
+	var parsed Type
+	if err := parsed.UnmarshalText([]byte("complex128")); err == nil {
+		t.Errorf("Expected error parsing unknown type.")
+	}
+}
@@ -0,0 +1,318 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Integer types a field may be declared as, whose range is checked.
+var integerTypes = map[string]bool{
+	"int8": true, "int16": true, "int32": true, "int64": true,
+	"uint8": true, "uint16": true, "uint32": true, "uint64": true,
+}
+
+// A constraint observed of values, named after the go-playground/validator
+// tag checking it: required, min, max or oneof. Values are those of oneof.
+type constraint struct {
+	name   string
+	arg    string
+	values []string
+}
+
+// Constraints observed of a field: those of lists and those of their
+// elements, or of the field's value if it isn't a list. Kind is the kind of
+// the values' type: string, number, interface or struct.
+type constraints struct {
+	list  []constraint
+	value []constraint
+	kind  string
+}
+
+// Returns the constraints observed of a field's values given the name of
+// their Go type and options of its tag. Values are required if they were
+// present in every object and never zero: empty strings and lists, null or
+// zero. Numbers have the range observed and strings and lists the range of
+// their lengths.
+func (f *formatter) constraints(t, parent *Tree, typ string, options []string) (c constraints) {
+	required := !t.optional(parent)
+	if t.List {
+		if required {
+			c.list = append(c.list, constraint{name: "required"})
+		}
+		c.list = append(c.list, lengths(t.Stats, 0)...)
+		required = false
+	}
+
+	s := t.values()
+	switch {
+	case s == nil || len(options) > 0:
+		// Quoted numbers and bools aren't checked.
+	case t.Type == Struct:
+		c.kind = "struct"
+	case t.Type == String && !t.base64():
+		values, enum := t.enumValues()
+		enum = enum && t.detector() == nil
+		if typ != "string" && !enum {
+			break
+		}
+
+		c.kind = "string"
+		if required && s.MinLength != nil && *s.MinLength > 0 {
+			c.value = append(c.value, constraint{name: "required"})
+		}
+		// The values of enums imply their lengths.
+		if enum {
+			c.value = append(c.value, constraint{name: "oneof", values: values})
+		} else {
+			c.value = append(c.value, lengths(s, 1)...)
+		}
+	case integerTypes[typ] && s.Min != nil && s.Max != nil:
+		c.kind = "number"
+		if required && (s.Min.Sign() > 0 || s.Max.Sign() < 0) {
+			c.value = append(c.value, constraint{name: "required"})
+		}
+		// Unsigned integers are never less than 0.
+		if s.Min.Sign() != 0 || !strings.HasPrefix(typ, "uint") {
+			c.value = append(c.value, constraint{name: "min", arg: s.Min.String()})
+		}
+		c.value = append(c.value, constraint{name: "max", arg: s.Max.String()})
+	case typ == "float64" && s.MinFloat != nil && s.MaxFloat != nil:
+		c.kind = "number"
+		if required && (*s.MinFloat > 0 || *s.MaxFloat < 0) {
+			c.value = append(c.value, constraint{name: "required"})
+		}
+		c.value = append(c.value,
+			constraint{name: "min", arg: strconv.FormatFloat(*s.MinFloat, 'g', -1, 64)},
+			constraint{name: "max", arg: strconv.FormatFloat(*s.MaxFloat, 'g', -1, 64)})
+	case typ == Interface.String():
+		c.kind = "interface"
+		if required && s.Kinds["null"] == 0 && len(s.Kinds) > 0 {
+			c.value = append(c.value, constraint{name: "required"})
+		}
+	}
+	return
+}
+
+// Returns the range of lengths observed, if lengths of at least min were.
+func lengths(s *Stats, min int) (c []constraint) {
+	if s == nil || s.MinLength == nil || s.MaxLength == nil {
+		return nil
+	}
+	if *s.MinLength >= min && *s.MinLength > 0 {
+		c = append(c, constraint{name: "min", arg: strconv.Itoa(*s.MinLength)})
+	}
+	return append(c, constraint{name: "max", arg: strconv.Itoa(*s.MaxLength)})
+}
+
+// Returns the value of the validate tag of a field, empty if it has no
+// constraints. The elements of lists are validated with dive, as are structs
+// so their fields are.
+func (c constraints) tag(t *Tree) string {
+	var tags []string
+	for _, x := range c.list {
+		tags = append(tags, x.tag())
+	}
+	if t.List && (len(c.value) > 0 || c.kind == "struct") {
+		tags = append(tags, "dive")
+	}
+	for _, x := range c.value {
+		if x.name == "oneof" && x.tag() == "" {
+			continue
+		}
+		tags = append(tags, x.tag())
+	}
+	return strings.Join(tags, ",")
+}
+
+// Returns a constraint as a validator tag, empty if it can't be written as
+// one. Commas and pipes are escaped, and values with spaces are quoted.
+func (x constraint) tag() string {
+	switch x.name {
+	case "required":
+		return x.name
+	case "oneof":
+		var values []string
+		for _, v := range x.values {
+			if strings.Contains(v, "'") {
+				return ""
+			}
+
+			v = strings.Replace(strings.Replace(v, ",", "0x2C", -1), "|", "0x7C", -1)
+			if v == "" || strings.Contains(v, " ") {
+				v = "'" + v + "'"
+			}
+			values = append(values, v)
+		}
+		return "oneof=" + strings.Join(values, " ")
+	}
+	return x.name + "=" + x.arg
+}
+
+// Declares a Validate method of the top level type checking the constraints
+// observed of its fields, the fields of nested structs and the elements of
+// lists. Fields of unions, tuples and recursive types aren't checked.
+func (f *formatter) validator(t *Tree) string {
+	c := &checker{f: f}
+	switch {
+	case t.Type == Struct && t.List:
+		c.elements(t, ".", "v", "", nil, constraints{kind: "struct"})
+	case t.Type == Struct:
+		c.fields(t, ".", "v", "", nil)
+	}
+
+	if len(c.checks) > 0 {
+		f.imports["errors"] = true
+		f.imports["strings"] = true
+	}
+
+	return f.execute("validate.tmpl", struct {
+		Name   string
+		Checks []*check
+	}{t.Name.String(), c.checks})
+}
+
+// A check of a Validate method: a loop over the elements of a list, with
+// checks of its own, or conditions of a value of which only the first it
+// violates is reported.
+type check struct {
+	Expr, Index, Element string
+	Checks               []*check
+
+	Conditions []condition
+}
+
+// A condition of a constraint which a value violates, and the message
+// reported: a format of its location and the constraint whose arguments are
+// the indices of the lists the value is in, or text if it's in none.
+type condition struct {
+	Name   string
+	Arg    string
+	Values []string
+
+	Kind, Expr string
+
+	Format, Text string
+	Args         []string
+}
+
+// Writes the checks of a Validate method.
+type checker struct {
+	f      *formatter
+	checks []*check
+
+	// Number of loops the checks are in, which name their variables.
+	depth int
+}
+
+// Writes checks of the fields of a struct. Locations are formats of the
+// location of values in the document, whose arguments are the indices of the
+// lists they're in.
+func (c *checker) fields(t *Tree, path, expr, location string, args []string) {
+	for _, child := range t.Children {
+		childPath := joinPath(path, string(child.Name))
+		field := expr + "." + child.Name.String()
+		loc := location + formatText(string(child.Name))
+
+		cs := c.f.checks[childPath]
+		if child.List {
+			c.check(cs.list, "list", field, loc, args)
+			c.elements(child, childPath, field, loc, args, cs)
+			continue
+		}
+
+		c.check(cs.value, cs.kind, field, loc, args)
+		if child.Type == Struct {
+			c.fields(child, childPath, field, loc+".", args)
+		}
+	}
+}
+
+// Writes a loop checking the elements of a list, if any are checked.
+func (c *checker) elements(t *Tree, path, expr, location string, args []string, cs constraints) {
+	depth := strconv.Itoa(c.depth)
+	loop := &check{Expr: expr, Index: "i" + depth, Element: "e" + depth}
+	loc := location + "[%d]"
+	args = append(args[:len(args):len(args)], loop.Index)
+
+	checks := c.checks
+	c.checks = nil
+	c.depth++
+
+	c.check(cs.value, cs.kind, loop.Element, loc, args)
+	if t.Type == Struct {
+		c.fields(t, path, loop.Element, loc+".", args)
+	}
+
+	c.depth--
+	loop.Checks = c.checks
+	c.checks = checks
+	if len(loop.Checks) > 0 {
+		c.checks = append(c.checks, loop)
+	}
+}
+
+// Writes checks of a value's constraints, reporting only the first it
+// violates.
+func (c *checker) check(cs []constraint, kind, expr, location string, args []string) {
+	var conditions []condition
+	for _, x := range cs {
+		var message string
+		switch x.name {
+		case "required":
+			message = "required"
+		case "oneof":
+			var values []string
+			for _, v := range x.values {
+				values = append(values, strconv.Quote(v))
+			}
+			message = "not one of " + strings.Join(values, ", ")
+		case "min", "max":
+			if kind == "string" {
+				c.f.imports["unicode/utf8"] = true
+			}
+
+			message = "less than " + x.arg
+			if x.name == "max" {
+				message = "greater than " + x.arg
+			}
+			if kind != "number" {
+				message = "length " + message
+			}
+		}
+
+		format := location + ": " + formatText(message)
+		if len(args) > 0 {
+			c.f.imports["fmt"] = true
+		}
+
+		conditions = append(conditions, condition{
+			Name:   x.name,
+			Arg:    x.arg,
+			Values: x.values,
+			Kind:   kind,
+			Expr:   expr,
+			Format: format,
+			Text:   strings.Replace(format, "%%", "%", -1),
+			Args:   args,
+		})
+	}
+	if len(conditions) > 0 {
+		c.checks = append(c.checks, &check{Conditions: conditions})
+	}
+}
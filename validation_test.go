@@ -0,0 +1,170 @@
+// JSONGen - A tool for generating native Golang types from JSON objects.
+// Copyright (C) 2014 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConstraintTag(t *testing.T) {
+	testCases := []struct {
+		Constraint constraint
+		Expected   string
+	}{
+		{constraint{name: "required"}, "required"},
+		{constraint{name: "min", arg: "-1.5"}, "min=-1.5"},
+		{constraint{name: "oneof", values: []string{"a", "b c", "d,e", "f|g", ""}}, "oneof=a 'b c' d0x2Ce f0x7Cg ''"},
+		{constraint{name: "oneof", values: []string{"a", "it's"}}, ""},
+	}
+
+	for _, testCase := range testCases {
+		if got := testCase.Constraint.tag(); got != testCase.Expected {
+			t.Errorf("Expected: %q Got: %q", testCase.Expected, got)
+		}
+	}
+}
+
+func TestValidationTags(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.validation = "tags"
+		c.enumMax = 10
+		c.enumSamples = 3
+	})()
+
+	testCases := []struct {
+		Source   string
+		Enum     bool
+		Expected []string
+	}{
+		{`[{"id": 3, "name": "ab", "tags": ["x"]}, {"id": 7, "name": "abcd", "tags": ["abc", "d"]}, {"id": 5, "name": "abc", "tags": ["yy", "z"], "note": "n"}]`, false, []string{
+			"\tId   int64    `json:\"id\" validate:\"required,min=3,max=7\"`\n",
+			"\tName string   `json:\"name\" validate:\"required,min=2,max=4\"`\n",
+			"\tNote string   `json:\"note\" validate:\"min=1,max=1\"`\n",
+			"\tTags []string `json:\"tags\" validate:\"required,min=1,max=2,dive,min=1,max=3\"`\n",
+		}},
+		// Zero isn't required, nor the minimum of unsigned integers.
+		{`[{"n": 0, "f": -0.5, "v": 1}, {"n": 4, "f": 2.25, "v": null}, {"n": 2, "f": 1.5, "v": "a"}]`, false, []string{
+			"\tF float64     `json:\"f\" validate:\"min=-0.5,max=2.25\"`\n",
+			"\tN int64       `json:\"n\" validate:\"min=0,max=4\"`\n",
+			"\tV interface{} `json:\"v\"`\n",
+		}},
+		{`[{"kind": "a b"}, {"kind": "c"}, {"kind": "a b"}]`, true, []string{
+			"\tKind Kind `json:\"kind\" validate:\"required,oneof='a b' c\"`\n",
+		}},
+		{`[{"items": [{"sku": "a"}]}]`, false, []string{
+			"`json:\"items\" validate:\"required,min=1,max=1,dive\"`\n",
+		}},
+	}
+
+	for _, testCase := range testCases {
+		config.enum = testCase.Enum
+		tree, err := Parse(testCase.Source)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		formatted, err := tree.Format()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, expected := range testCase.Expected {
+			if !strings.Contains(string(formatted), expected) {
+				t.Errorf("Source: %s Expected: %q Got: %q", testCase.Source, expected, formatted)
+			}
+		}
+	}
+}
+
+func TestValidator(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.validation = "method"
+	})()
+
+	testCases := []struct {
+		Source   string
+		Expected []string
+	}{
+		{`1`, []string{
+			"func (v Order) Validate() error {\n\treturn nil\n}\n",
+		}},
+		{`{"id": 2, "lines": [{"sku": "ab"}]}`, []string{
+			"import (\n\t\"errors\"\n\t\"fmt\"\n\t\"strings\"\n\t\"unicode/utf8\"\n)\n",
+			"\tif v.Id == 0 {\n\t\terrs = append(errs, \"id: required\")\n\t} else if v.Id < 2 {\n",
+			"\t} else if len(v.Lines) > 1 {\n\t\terrs = append(errs, \"lines: length greater than 1\")\n\t}\n",
+			"\tfor i0, e0 := range v.Lines {\n\t\tif e0.Sku == \"\" {\n\t\t\terrs = append(errs, fmt.Sprintf(\"lines[%d].sku: required\", i0))\n",
+			"\t\t} else if utf8.RuneCountInString(e0.Sku) < 2 {\n",
+			"\t\treturn errors.New(strings.Join(errs, \"; \"))\n",
+		}},
+		{`[{"a%": {"b": true}}]`, []string{
+			"func (v Order) Validate() error {\n\treturn nil\n}\n",
+		}},
+		{`[{"a%": {"b": -1}}]`, []string{
+			"\tfor i0, e0 := range v {\n\t\tif e0.A.B == 0 {\n\t\t\terrs = append(errs, fmt.Sprintf(\"[%d].a%%.b: required\", i0))\n",
+		}},
+	}
+
+	for _, testCase := range testCases {
+		tree, err := Parse(testCase.Source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree.Name = "Order"
+
+		formatted, err := tree.Format()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, expected := range testCase.Expected {
+			if !strings.Contains(string(formatted), expected) {
+				t.Errorf("Source: %s Expected: %q Got: %q", testCase.Source, expected, formatted)
+			}
+		}
+	}
+}
+
+func TestValidatorVet(t *testing.T) {
+	defer withConfig(func(c *Config) {
+		c.validation = "method"
+		c.tuples = true
+		c.tupleMax = 8
+		c.enum = true
+		c.enumMax = 10
+		c.enumSamples = 1
+	})()
+
+	sources := []string{
+		`{"statuses": ["a", "b", "a"], "pair": ["x", 1], "lines": [{"sku": "ab", "qty": [1, 2]}]}`,
+		`[{"id": 1, "name": "a"}, {"id": 2, "tags": ["x"]}]`,
+	}
+
+	for _, source := range sources {
+		tree, err := Parse(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree.Name = "Order"
+
+		formatted, err := tree.Format()
+		if err != nil {
+			t.Fatal(err)
+		}
+		vet(t, formatted)
+	}
+}